@@ -17,6 +17,11 @@ import (
 	"github.com/SevenTV/GQL/src/configure"
 	"github.com/SevenTV/GQL/src/global"
 	"github.com/SevenTV/GQL/src/instance"
+	"github.com/SevenTV/GQL/src/jobs"
+	"github.com/SevenTV/GQL/src/search"
+	"github.com/SevenTV/GQL/src/stats"
+	"github.com/SevenTV/GQL/src/storage"
+	"github.com/SevenTV/GQL/src/trending"
 	"github.com/bugsnag/panicwrap"
 	"github.com/sirupsen/logrus"
 )
@@ -78,6 +83,15 @@ func main() {
 			logrus.WithError(err).Fatal("failed to connect to mongo")
 		}
 
+		// Create the indexes this service relies on, which includes the TTLs of lapsing records
+		ctx, cancel = context.WithTimeout(gCtx, time.Minute)
+		for _, ref := range configure.Indexes {
+			if _, err = mongoInst.Collection(ref.Collection).Indexes().CreateOne(ctx, ref.Index); err != nil {
+				logrus.WithError(err).WithField("collection", ref.Collection).Error("mongo, failed to create index")
+			}
+		}
+		cancel()
+
 		ctx, cancel = context.WithTimeout(gCtx, time.Second*15)
 		redisInst, err := redis.Setup(ctx, redis.SetupOptions{
 			Username:  config.Redis.Username,
@@ -93,6 +107,36 @@ func main() {
 		gCtx.Inst().Mongo = instance.WrapMongo(mongoInst)
 		gCtx.Inst().Redis = redisInst
 		gCtx.Inst().Query = query.New(mongoInst, redisInst)
+
+		// Set up Storage
+		storageInst, err := storage.New(gCtx)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to set up storage")
+		}
+
+		gCtx.Inst().Storage = storageInst
+
+		// Set up Jobs
+		jobsInst, err := jobs.New(gCtx)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to set up jobs")
+		}
+
+		gCtx.Inst().Jobs = jobsInst
+
+		// Set up Trending
+		gCtx.Inst().Trending = trending.New(gCtx)
+
+		// Set up Stats
+		gCtx.Inst().Stats = stats.New(gCtx)
+
+		// Set up Search
+		searchInst, err := search.New(gCtx)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to set up search")
+		}
+
+		gCtx.Inst().Search = searchInst
 	}
 
 	serverDone := api.New(gCtx)
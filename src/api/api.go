@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"time"
 
 	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/GQL/src/api/middleware"
+	"github.com/SevenTV/GQL/src/api/rest"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	"github.com/SevenTV/GQL/src/global"
 	"github.com/fasthttp/router"
@@ -16,23 +18,53 @@ func New(gCtx global.Context) <-chan struct{} {
 	done := make(chan struct{})
 	loader := loaders.New(gCtx)
 
-	gql := GqlHandler(gCtx, loader)
+	gqlV3, execV3 := GqlHandler(gCtx, loader)
+	gqlV2, _ := GqlHandlerV2(gCtx, loader)
+	gql := map[string]func(ctx *fasthttp.RequestCtx){
+		"v3": gqlV3,
+		"v2": gqlV2,
+	}
 
 	router := router.New()
 
 	router.RedirectTrailingSlash = true
-	mid := func(ctx *fasthttp.RequestCtx) {
+	auth := func(ctx *fasthttp.RequestCtx) {
 		if err := middleware.Auth(gCtx)(ctx); err != nil {
 			ctx.Response.Header.Add("X-Auth-Failure", err.Error())
-			goto handler
 		}
+	}
 
-	handler:
-		gql(ctx)
+	mid := func(ctx *fasthttp.RequestCtx) {
+		v, _ := ctx.UserValue("v").(string)
+		h, ok := gql[v]
+		if !ok {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			ctx.SetContentType("application/json")
+			ctx.SetBodyString(`{"errors":[{"message":"unknown api version"}]}`)
+			return
+		}
+
+		auth(ctx)
+		h(ctx)
 	}
 	router.GET("/{v}", mid)
 	router.POST("/{v}", mid)
 
+	// REST Gateway
+	routes, err := rest.Routes(gCtx)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to load rest routes")
+	}
+	for _, route := range routes {
+		h := route.Handler(execV3, func(ctx *fasthttp.RequestCtx) context.Context {
+			return requestContext(gCtx, loader, ctx)
+		})
+		router.Handle(route.Method, "/v3/rest"+route.Path, func(ctx *fasthttp.RequestCtx) {
+			auth(ctx)
+			h(ctx)
+		})
+	}
+
 	router.HandleOPTIONS = true
 	server := fasthttp.Server{
 		Handler: func(ctx *fasthttp.RequestCtx) {
@@ -68,10 +100,11 @@ func New(gCtx global.Context) <-chan struct{} {
 
 			router.Handler(ctx)
 		},
-		ReadTimeout:     time.Second * 10,
-		WriteTimeout:    time.Second * 10,
-		CloseOnShutdown: true,
-		Name:            "7TV - GQL",
+		ReadTimeout:        time.Second * 10,
+		MaxRequestBodySize: int(maxUploadSize(gCtx)),
+		WriteTimeout:       time.Second * 10,
+		CloseOnShutdown:    true,
+		Name:               "7TV - GQL",
 	}
 
 	go func() {
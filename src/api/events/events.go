@@ -8,6 +8,11 @@ import (
 )
 
 func Publish(ctx global.Context, objectType string, id primitive.ObjectID) {
+	PublishMessage(ctx, objectType, id, "1")
+}
+
+// PublishMessage notifies the subscribers of an object, passing them a message such as the ID of a child object which changed
+func PublishMessage(ctx global.Context, objectType string, id primitive.ObjectID, msg string) {
 	k := ctx.Inst().Redis.ComposeKey("events", fmt.Sprintf("sub:%s:%s", objectType, id.Hex()))
-	ctx.Inst().Redis.RawClient().Publish(ctx, k.String(), "1")
+	ctx.Inst().Redis.RawClient().Publish(ctx, k.String(), msg)
 }
@@ -12,10 +12,14 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/SevenTV/GQL/graph/generated"
+	generatedv2 "github.com/SevenTV/GQL/graph/v2/generated"
 	"github.com/SevenTV/GQL/src/api/middleware"
+	resolversv2 "github.com/SevenTV/GQL/src/api/v2/gql/resolvers"
+	typesv2 "github.com/SevenTV/GQL/src/api/v2/gql/types"
 	"github.com/SevenTV/GQL/src/api/v3/gql/cache"
 	"github.com/SevenTV/GQL/src/api/v3/gql/complexity"
 	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/introspection"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	middlewarev3 "github.com/SevenTV/GQL/src/api/v3/gql/middleware"
 	"github.com/SevenTV/GQL/src/api/v3/gql/resolvers"
@@ -28,34 +32,58 @@ import (
 	"github.com/valyala/fasthttp/fasthttpadaptor"
 )
 
-func GqlHandler(gCtx global.Context, loader *loaders.Loaders) func(ctx *fasthttp.RequestCtx) {
+func GqlHandler(gCtx global.Context, loader *loaders.Loaders) (func(ctx *fasthttp.RequestCtx), *executor.Executor) {
 	schema := generated.NewExecutableSchema(generated.Config{
 		Resolvers:  resolvers.New(types.Resolver{Ctx: gCtx}),
 		Directives: middlewarev3.New(gCtx),
 		Complexity: complexity.New(gCtx),
 	})
+
+	return gqlHandler(gCtx, loader, schema)
+}
+
+// GqlHandlerV2 serves the legacy v2 schema, backed by the same data as v3
+func GqlHandlerV2(gCtx global.Context, loader *loaders.Loaders) (func(ctx *fasthttp.RequestCtx), *executor.Executor) {
+	schema := generatedv2.NewExecutableSchema(generatedv2.Config{
+		Resolvers: resolversv2.New(typesv2.Resolver{Ctx: gCtx}),
+	})
+
+	return gqlHandler(gCtx, loader, schema)
+}
+
+func gqlHandler(gCtx global.Context, loader *loaders.Loaders, schema graphql.ExecutableSchema) (func(ctx *fasthttp.RequestCtx), *executor.Executor) {
 	srv := handler.New(schema)
 	exec := executor.New(schema)
 
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
-	srv.Use(extension.Introspection{})
-
-	srv.Use(&extension.ComplexityLimit{
-		Func: func(ctx context.Context, rc *graphql.OperationContext) int {
-			return 75
-		},
-	})
-
-	srv.Use(extension.Introspection{})
-	srv.Use(extension.AutomaticPersistedQuery{
-		Cache: cache.NewRedisCache(gCtx, "", time.Hour*6),
+	srv.AddTransport(transport.MultipartForm{
+		MaxUploadSize: maxUploadSize(gCtx),
+		MaxMemory:     32 << 20,
 	})
+	// Extensions are registered on both the http server and the standalone executor,
+	// so that websocket and rest requests are subject to the same rules
+	for _, ext := range []graphql.HandlerExtension{
+		introspection.New(gCtx),
+		&extension.ComplexityLimit{
+			Func: func(ctx context.Context, rc *graphql.OperationContext) int {
+				return 75
+			},
+		},
+		extension.AutomaticPersistedQuery{
+			Cache: cache.NewRedisCache(gCtx, "", time.Hour*6),
+		},
+	} {
+		srv.Use(ext)
+		exec.Use(ext)
+	}
 
-	srv.SetRecoverFunc(func(ctx context.Context, err interface{}) (userMessage error) {
+	recoverFunc := func(ctx context.Context, err interface{}) (userMessage error) {
 		logrus.Error("panic in handler: ", err)
 		return helpers.ErrInternalServerError
-	})
+	}
+	srv.SetRecoverFunc(recoverFunc)
+	exec.SetRecoverFunc(recoverFunc)
 
 	wsTransport := wsTransport.Websocket{
 		KeepAlivePingInterval: 10 * time.Second,
@@ -84,15 +112,35 @@ func GqlHandler(gCtx global.Context, loader *loaders.Loaders) func(ctx *fasthttp
 	}
 
 	return func(ctx *fasthttp.RequestCtx) {
-		lCtx := context.WithValue(context.WithValue(gCtx, loaders.LoadersKey, loader), helpers.UserKey, ctx.UserValue("user"))
+		lCtx := requestContext(gCtx, loader, ctx)
 
 		if wsTransport.Supports(ctx) {
 			wsTransport.Do(ctx, lCtx, exec)
+		} else if wantsPlayground(ctx) {
+			Playground(ctx)
 		} else {
 			fasthttpadaptor.NewFastHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				srv.ServeHTTP(w, r.WithContext(lCtx))
 			}))(ctx)
 		}
 
+	}, exec
+}
+
+// maxUploadSize returns the largest request body accepted, leaving headroom for the operation itself
+func maxUploadSize(gCtx global.Context) int64 {
+	size := gCtx.Config().Upload.MaxSize
+	if size <= 0 {
+		size = 7000000
 	}
+
+	return size + 1<<20
+}
+
+// requestContext creates the context that operations of a request are executed with
+func requestContext(gCtx global.Context, loader *loaders.Loaders, ctx *fasthttp.RequestCtx) context.Context {
+	lCtx := context.WithValue(context.WithValue(gCtx, loaders.LoadersKey, loader), helpers.UserKey, ctx.UserValue("user"))
+
+	// The Accept header is used to pick image formats
+	return context.WithValue(lCtx, helpers.AcceptKey, string(ctx.Request.Header.Peek("Accept")))
 }
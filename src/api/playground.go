@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/SevenTV/Common/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+var playgroundPage = template.Must(template.New("graphiql").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>{{.title}}</title>
+    <link
+      rel="stylesheet"
+      href="https://cdn.jsdelivr.net/npm/graphiql@{{.version}}/graphiql.min.css"
+      crossorigin="anonymous"
+    />
+  </head>
+  <body style="margin: 0;">
+    <div id="graphiql" style="height: 100vh;"></div>
+
+    <script src="https://cdn.jsdelivr.net/npm/react@17.0.2/umd/react.production.min.js" crossorigin="anonymous"></script>
+    <script src="https://cdn.jsdelivr.net/npm/react-dom@17.0.2/umd/react-dom.production.min.js" crossorigin="anonymous"></script>
+    <script src="https://cdn.jsdelivr.net/npm/graphiql@{{.version}}/graphiql.min.js" crossorigin="anonymous"></script>
+
+    <script>
+      const url = location.protocol + '//' + location.host + {{.endpoint}};
+      const wsProto = location.protocol == 'https:' ? 'wss:' : 'ws:';
+      const subscriptionUrl = wsProto + '//' + location.host + {{.endpoint}};
+
+      // The page carries no credentials: an Authorization header can be set in the header editor,
+      // which GraphiQL keeps in the local storage of this origin
+      const fetcher = GraphiQL.createFetcher({
+        url,
+        subscriptionUrl,
+      });
+
+      ReactDOM.render(
+        React.createElement(GraphiQL, { fetcher: fetcher, headerEditorEnabled: true, shouldPersistHeaders: true }),
+        document.getElementById('graphiql'),
+      );
+    </script>
+  </body>
+</html>
+`))
+
+// wantsPlayground returns whether or not the request comes from a browser asking for an html document.
+// Requests carrying a query are executed, as when opening a GET query link in a browser
+func wantsPlayground(ctx *fasthttp.RequestCtx) bool {
+	return ctx.IsGet() && !ctx.QueryArgs().Has("query") && strings.Contains(utils.B2S(ctx.Request.Header.Peek("Accept")), "text/html")
+}
+
+// Playground serves a GraphiQL page bound to the requested api version.
+// Browsers don't send an Authorization header when navigating, so the page is served without a token
+func Playground(ctx *fasthttp.RequestCtx) {
+	buf := bytes.Buffer{}
+	if err := playgroundPage.Execute(&buf, map[string]string{
+		"title":    "7TV - GQL",
+		"version":  "1.5.16",
+		"endpoint": utils.B2S(ctx.Path()),
+	}); err != nil {
+		logrus.WithError(err).Error("failed to render playground")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("text/html; charset=utf-8")
+	ctx.SetBody(buf.Bytes())
+}
@@ -0,0 +1,287 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+//go:embed routes/*.gql
+var embeddedRoutes embed.FS
+
+// Route is a rest endpoint backed by a persisted graphql document
+//
+// Path parameters (i.e /emotes/{id}) and query string parameters are passed to the document as variables of the same name.
+// Query string values are converted to the type of their variable; lists and input objects are given as JSON
+type Route struct {
+	Method   string
+	Path     string
+	Document string
+
+	// The response key of the document's root field, whose value is returned
+	field string
+}
+
+var routeHeader = regexp.MustCompile(`^#\s*(GET|POST|PUT|PATCH|DELETE)\s+(/\S*)`)
+
+// Routes returns the embedded route definitions followed by those defined in config
+func Routes(gCtx global.Context) ([]Route, error) {
+	routes := []Route{}
+
+	files, err := fs.Glob(embeddedRoutes, "routes/*.gql")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		b, err := embeddedRoutes.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		route, err := parseRoute(utils.B2S(b))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err.Error())
+		}
+		if route.field, err = rootField(route.Document); err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err.Error())
+		}
+		routes = append(routes, route)
+	}
+
+	for _, r := range gCtx.Config().Rest.Routes {
+		method := strings.ToUpper(r.Method)
+		if method == "" {
+			method = fasthttp.MethodGet
+		}
+
+		route := Route{
+			Method:   method,
+			Path:     r.Path,
+			Document: r.Document,
+		}
+		if route.field, err = rootField(route.Document); err != nil {
+			return nil, fmt.Errorf("%s %s: %s", method, r.Path, err.Error())
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+// parseRoute reads a route from a document whose first line is a "# METHOD /path" header
+func parseRoute(doc string) (Route, error) {
+	line, _, _ := bufio.NewReader(strings.NewReader(doc)).ReadLine()
+
+	m := routeHeader.FindStringSubmatch(string(line))
+	if m == nil {
+		return Route{}, fmt.Errorf("missing route header")
+	}
+
+	return Route{
+		Method:   m[1],
+		Path:     m[2],
+		Document: doc,
+	}, nil
+}
+
+// rootField returns the response key of the only root field selected by a document,
+// as a route returns the value of that field alone
+func rootField(doc string) (string, error) {
+	query, err := parser.ParseQuery(&ast.Source{Input: doc})
+	if err != nil {
+		return "", err
+	}
+	if len(query.Operations) != 1 {
+		return "", fmt.Errorf("document must have exactly one operation")
+	}
+
+	sel := query.Operations[0].SelectionSet
+	if len(sel) != 1 {
+		return "", fmt.Errorf("operation must select exactly one root field")
+	}
+	field, ok := sel[0].(*ast.Field)
+	if !ok {
+		return "", fmt.Errorf("root selection must be a field")
+	}
+	if field.Alias != "" {
+		return field.Alias, nil
+	}
+	return field.Name, nil
+}
+
+var pathParam = regexp.MustCompile(`{(\w+)}`)
+
+// Handler executes the route's document against the executor and writes the result of its root field
+func (r Route) Handler(exec *executor.Executor, ctxFn func(ctx *fasthttp.RequestCtx) context.Context) func(ctx *fasthttp.RequestCtx) {
+	params := []string{}
+	for _, m := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+		params = append(params, m[1])
+	}
+
+	// Documents which cannot be parsed were rejected as the route was loaded
+	types := map[string]*ast.Type{}
+	if doc, err := parser.ParseQuery(&ast.Source{Input: r.Document}); err == nil {
+		for _, op := range doc.Operations {
+			for _, v := range op.VariableDefinitions {
+				types[v.Variable] = v.Type
+			}
+		}
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		start := graphql.Now()
+
+		variables := map[string]interface{}{}
+		ctx.QueryArgs().VisitAll(func(key, value []byte) {
+			variables[string(key)] = coerceVariable(types[string(key)], string(value))
+		})
+		for _, p := range params {
+			variables[p], _ = ctx.UserValue(p).(string)
+		}
+
+		lCtx, cancel := context.WithTimeout(ctxFn(ctx), time.Second*10)
+		defer cancel()
+
+		lCtx = graphql.StartOperationTrace(lCtx)
+		rawParams := &graphql.RawParams{
+			Query:     r.Document,
+			Variables: variables,
+			ReadTime: graphql.TraceTiming{
+				Start: start,
+				End:   graphql.Now(),
+			},
+		}
+
+		rc, errs := exec.CreateOperationContext(lCtx, rawParams)
+		if errs != nil {
+			writeResponse(ctx, fasthttp.StatusBadRequest, exec.DispatchError(graphql.WithOperationContext(lCtx, rc), errs))
+			return
+		}
+
+		responses, lCtx := exec.DispatchOperation(lCtx, rc)
+		resp := responses(lCtx)
+
+		// Unwrap the root field so consumers receive the requested object directly
+		data := map[string]json.RawMessage{}
+		_ = json.Unmarshal(resp.Data, &data)
+
+		v := data[r.field]
+		if len(resp.Errors) > 0 {
+			// Errors preventing the object from being returned decide the status
+			status := fasthttp.StatusBadRequest
+			if v == nil || string(v) == "null" {
+				status = errorStatus(resp.Errors[0])
+			}
+
+			writeResponse(ctx, status, resp)
+			return
+		}
+		if v == nil {
+			writeResponse(ctx, fasthttp.StatusInternalServerError, resp)
+			return
+		}
+		if string(v) == "null" {
+			writeResponse(ctx, fasthttp.StatusNotFound, resp)
+			return
+		}
+
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		ctx.SetContentType("application/json")
+		ctx.SetBody(v)
+	}
+}
+
+// coerceVariable converts a query string value to the type of the variable it is passed as,
+// leaving it as is when it cannot be, for the executor to report
+func coerceVariable(typ *ast.Type, value string) interface{} {
+	switch {
+	case typ == nil:
+		return value
+	case typ.Elem != nil || strings.HasPrefix(value, "{"):
+		// Numbers are kept as json.Number, which are accepted as either Int or Float
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err == nil {
+			return v
+		}
+	case typ.NamedType == "Boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+
+	return value
+}
+
+// errorStatus returns the http status matching an error of a graphql response
+func errorStatus(err *gqlerror.Error) int {
+	cause := err.Unwrap()
+	if cause == nil {
+		// The document or its variables are invalid
+		return fasthttp.StatusBadRequest
+	}
+
+	var apiErr errors.APIError
+	if stderrors.As(cause, &apiErr) {
+		switch apiErr.Code() {
+		case errors.ErrUnauthorized().Code():
+			return fasthttp.StatusUnauthorized
+		case errors.ErrInsufficientPrivilege().Code():
+			return fasthttp.StatusForbidden
+		case errors.ErrUnknownEmote().Code(), errors.ErrUnknownEmoteSet().Code(), errors.ErrUnknownUser().Code(),
+			errors.ErrUnknownRole().Code(), errors.ErrUnknownReport().Code(), errors.ErrUnknownMessage().Code(), errors.ErrUnknownBan().Code():
+			return fasthttp.StatusNotFound
+		case errors.ErrRateLimited().Code():
+			return fasthttp.StatusTooManyRequests
+		case errors.ErrInternalServerError().Code(), errors.ErrInternalField().Code():
+			return fasthttp.StatusInternalServerError
+		}
+		return fasthttp.StatusBadRequest
+	}
+
+	switch cause {
+	case helpers.ErrUnauthorized:
+		return fasthttp.StatusUnauthorized
+	case helpers.ErrAccessDenied:
+		return fasthttp.StatusForbidden
+	case helpers.ErrUnknownEmote, helpers.ErrUnknownUser, helpers.ErrUnknownRole, helpers.ErrUnknownReport:
+		return fasthttp.StatusNotFound
+	case helpers.ErrBadObjectID, helpers.ErrBadInt, helpers.ErrDontBeSilly:
+		return fasthttp.StatusBadRequest
+	}
+	return fasthttp.StatusInternalServerError
+}
+
+func writeResponse(ctx *fasthttp.RequestCtx, status int, resp *graphql.Response) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		logrus.WithError(err).Error("rest, failed to encode response")
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetStatusCode(status)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(b)
+}
@@ -0,0 +1,149 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/aggregations"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FetchEmotesByVersionID: Retrieve emotes by the IDs of their versions
+//
+// Returned emotes have their ID set to the matched version, in the order of the given IDs
+func FetchEmotesByVersionID(gCtx global.Context, ctx context.Context, ids []primitive.ObjectID) ([]*structures.Emote, error) {
+	emotes, err := gCtx.Inst().Query.Emotes(ctx, bson.M{"versions.id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[primitive.ObjectID]*structures.Emote)
+	for _, e := range emotes {
+		for _, ver := range e.Versions {
+			emote := *e
+			emote.ID = ver.ID
+			m[ver.ID] = &emote
+		}
+	}
+
+	result := []*structures.Emote{}
+	for _, id := range ids {
+		if e, ok := m[id]; ok {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// FetchEmoteSet: Retrieve an emote set along with its emotes
+func FetchEmoteSet(gCtx global.Context, ctx context.Context, id primitive.ObjectID) (*structures.EmoteSet, error) {
+	set := &structures.EmoteSet{}
+	if err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(ctx, bson.M{"_id": id}).Decode(set); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(set.Emotes))
+	for i, ae := range set.Emotes {
+		ids[i] = ae.ID
+	}
+	emotes, err := FetchEmotesByVersionID(gCtx, ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[primitive.ObjectID]*structures.Emote)
+	for _, e := range emotes {
+		m[e.ID] = e
+	}
+	for _, ae := range set.Emotes {
+		ae.Emote = m[ae.ID]
+	}
+
+	return set, nil
+}
+
+// FetchGlobalEmoteSet: Retrieve the system's global emote set
+func FetchGlobalEmoteSet(gCtx global.Context, ctx context.Context) *structures.EmoteSet {
+	sys := gCtx.Inst().Mongo.System(ctx)
+	if sys.EmoteSetID.IsZero() {
+		return nil
+	}
+
+	set := &structures.EmoteSet{}
+	if err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(ctx, bson.M{"_id": sys.EmoteSetID}).Decode(set); err != nil {
+		logrus.WithError(err).Error("mongo, couldn't fetch the global emote set")
+		return nil
+	}
+	return set
+}
+
+// FetchUsers: Retrieve users along with their roles
+func FetchUsers(gCtx global.Context, ctx context.Context, filter bson.M) ([]*structures.User, error) {
+	cur, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Aggregate(ctx, aggregations.Combine(
+		mongo.Pipeline{{{Key: "$match", Value: filter}}},
+		aggregations.UserRelationRoles,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*structures.User{}
+	if err = cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FetchUser: Retrieve a single user along with their roles
+func FetchUser(gCtx global.Context, ctx context.Context, filter bson.M) (*structures.User, error) {
+	users, err := FetchUsers(gCtx, ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return users[0], nil
+}
+
+// FetchUserChannelSet: Retrieve the emote set bound to a user's twitch connection
+func FetchUserChannelSet(gCtx global.Context, ctx context.Context, user *structures.User) *structures.EmoteSet {
+	for _, con := range user.Connections {
+		if con.Platform != structures.UserConnectionPlatformTwitch || con.EmoteSetID.IsZero() {
+			continue
+		}
+
+		set, err := FetchEmoteSet(gCtx, ctx, con.EmoteSetID)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				logrus.WithError(err).Error("mongo, couldn't fetch user's channel emote set")
+			}
+			return nil
+		}
+
+		// Legacy clients have no placeholder for emotes they may not see
+		emotes := set.Emotes[:0]
+		for _, ae := range set.Emotes {
+			if ae.Emote == nil || CanSeeEmote(ctx, ae.Emote) {
+				emotes = append(emotes, ae)
+			}
+		}
+		set.Emotes = emotes
+		return set
+	}
+	return nil
+}
+
+// BringForwardDefaultVersion: Set the emote's ID to that of its default version, which is kept first,
+// or of the first version after it which is live
+func BringForwardDefaultVersion(e *structures.Emote) {
+	for _, ver := range e.Versions {
+		if ver.State.Lifecycle == structures.EmoteLifecycleLive {
+			e.ID = ver.ID
+			return
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/GQL/graph/v2/model"
+	v3helpers "github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Legacy emote visibility bits
+const (
+	EmoteVisibilityPrivate int = 1 << iota
+	EmoteVisibilityGlobal
+	EmoteVisibilityUnlisted
+	EmoteVisibilityOverrideBTTV
+	EmoteVisibilityOverrideFFZ
+	EmoteVisibilityOverrideTwitchGlobal
+	EmoteVisibilityOverrideTwitchSubscriber
+	EmoteVisibilityZeroWidth
+	EmoteVisibilityPermanentlyUnlisted
+)
+
+var emoteVisibilitySimpleList = []struct {
+	bit  int
+	name string
+}{
+	{EmoteVisibilityPrivate, "PRIVATE"},
+	{EmoteVisibilityGlobal, "GLOBAL"},
+	{EmoteVisibilityUnlisted, "UNLISTED"},
+	{EmoteVisibilityZeroWidth, "ZERO_WIDTH"},
+	{EmoteVisibilityPermanentlyUnlisted, "PERMANENTLY_UNLISTED"},
+}
+
+// EmoteStructureToModel: Transform an emote structure to a legacy GQL model
+//
+// The emote's ID must be set to the version that should be represented
+func EmoteStructureToModel(ctx global.Context, s *structures.Emote, globalSet *structures.EmoteSet) *model.Emote {
+	var ver *structures.EmoteVersion
+	for _, v := range s.Versions {
+		if v.ID == s.ID {
+			ver = v
+			break
+		}
+	}
+	if ver == nil {
+		ver = &structures.EmoteVersion{ID: s.ID}
+	}
+
+	// Compute legacy visibility
+	visibility := 0
+	if s.Flags&structures.EmoteFlagsPrivate != 0 {
+		visibility |= EmoteVisibilityPrivate
+	}
+	if s.Flags&structures.EmoteFlagsZeroWidth != 0 {
+		visibility |= EmoteVisibilityZeroWidth
+	}
+	if !ver.State.Listed {
+		visibility |= EmoteVisibilityUnlisted
+	}
+	if globalSet != nil {
+		for _, ae := range globalSet.Emotes {
+			if ae.ID == ver.ID {
+				visibility |= EmoteVisibilityGlobal
+				break
+			}
+		}
+	}
+	simple := []string{}
+	for _, v := range emoteVisibilitySimpleList {
+		if visibility&v.bit != 0 {
+			simple = append(simple, v.name)
+		}
+	}
+
+	// Legacy status codes have processing and pending swapped
+	status := int(ver.State.Lifecycle)
+	switch ver.State.Lifecycle {
+	case structures.EmoteLifecycleProcessing:
+		status = 0
+	case structures.EmoteLifecyclePending:
+		status = 1
+	}
+
+	// Legacy clients consume WEBP sizes only
+	width := []int{}
+	height := []int{}
+	urls := [][]string{}
+	for _, f := range ver.Formats {
+		if f.Name != structures.EmoteFormatNameWEBP {
+			continue
+		}
+		for _, im := range f.Files {
+			if ver.FrameCount > 1 && !im.Animated {
+				continue
+			}
+			width = append(width, int(im.Width))
+			height = append(height, int(im.Height))
+
+			// Files of private emotes are only reachable through signed URLs
+			path := fmt.Sprintf("emote/%s/%s", ver.ID.Hex(), im.Name)
+			url := fmt.Sprintf("https://%s/%s", ctx.Config().CdnURL, path)
+			if s.Flags&structures.EmoteFlagsPrivate != 0 {
+				url += v3helpers.CdnSignature(ctx, path)
+			}
+			urls = append(urls, []string{im.Name[:1], url})
+		}
+	}
+
+	return &model.Emote{
+		ID:               ver.ID.Hex(),
+		Name:             s.Name,
+		OwnerID:          s.OwnerID.Hex(),
+		Visibility:       visibility,
+		VisibilitySimple: simple,
+		Mime:             string(structures.EmoteFormatNameWEBP),
+		Status:           status,
+		Tags:             utils.Ternary(s.Tags != nil, s.Tags, []string{}).([]string),
+		CreatedAt:        ver.ID.Timestamp().Format(time.RFC3339),
+		Width:            width,
+		Height:           height,
+		Urls:             urls,
+	}
+}
+
+// UserStructureToModel: Transform a user structure to a legacy GQL model
+//
+// The channel emote set is used to populate the user's legacy channel emote list
+func UserStructureToModel(ctx global.Context, s *structures.User, set *structures.EmoteSet) *model.User {
+	var role *model.Role
+	if r := s.GetHighestRole(); r != nil {
+		role = RoleStructureToModel(ctx, r)
+	} else {
+		role = RoleStructureToModel(ctx, &structures.Role{ID: primitive.NilObjectID, Name: "Default"})
+	}
+
+	twitchID := ""
+	emoteSlots := 0
+	profileImageURL := ""
+	for _, con := range s.Connections {
+		if con.Platform != structures.UserConnectionPlatformTwitch {
+			continue
+		}
+		twitchID = con.ID
+		emoteSlots = int(con.EmoteSlots)
+		if d, err := con.DecodeTwitch(); err == nil {
+			profileImageURL = d.ProfileImageURL
+		} else {
+			logrus.WithError(err).Error("couldn't decode twitch user connection")
+		}
+		break
+	}
+
+	emoteIDs := []string{}
+	emoteAliases := [][]string{}
+	if set != nil {
+		for _, ae := range set.Emotes {
+			emoteIDs = append(emoteIDs, ae.ID.Hex())
+			if ae.Emote != nil && ae.Emote.Name != ae.Name {
+				emoteAliases = append(emoteAliases, []string{ae.ID.Hex(), ae.Name})
+			}
+		}
+	}
+
+	return &model.User{
+		ID:              s.ID.Hex(),
+		Rank:            0,
+		Description:     s.Biography,
+		Role:            role,
+		EmoteIds:        emoteIDs,
+		EmoteAliases:    emoteAliases,
+		EmoteSlots:      emoteSlots,
+		CreatedAt:       s.ID.Timestamp().Format(time.RFC3339),
+		TwitchID:        twitchID,
+		DisplayName:     utils.Ternary(len(s.DisplayName) > 0, s.DisplayName, s.Username).(string),
+		Login:           s.Username,
+		ProfileImageURL: profileImageURL,
+	}
+}
+
+// RoleStructureToModel: Transform a role structure to a legacy GQL model
+func RoleStructureToModel(ctx global.Context, s *structures.Role) *model.Role {
+	return &model.Role{
+		ID:       s.ID.Hex(),
+		Name:     s.Name,
+		Position: int(s.Position),
+		Color:    int(s.Color),
+		Allowed:  int(s.Allowed),
+		Denied:   int(s.Denied),
+	}
+}
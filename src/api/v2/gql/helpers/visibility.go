@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+)
+
+// CanSeeEmote: Whether the actor may see an emote, private emotes being restricted as they are in v3
+func CanSeeEmote(ctx context.Context, e *structures.Emote) bool {
+	return e.Flags&structures.EmoteFlagsPrivate == 0 || auth.CanSeePrivateEmotesOf(ctx, e.OwnerID)
+}
+
+// FilterVisibleEmotes: Remove the emotes which the actor may not see
+func FilterVisibleEmotes(ctx context.Context, emotes []*structures.Emote) []*structures.Emote {
+	result := make([]*structures.Emote, 0, len(emotes))
+	for _, e := range emotes {
+		if CanSeeEmote(ctx, e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package emote
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/GQL/graph/v2/generated"
+	"github.com/SevenTV/GQL/graph/v2/model"
+	"github.com/SevenTV/GQL/src/api/v2/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v2/gql/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Resolver struct {
+	types.Resolver
+}
+
+func New(r types.Resolver) generated.EmoteResolver {
+	return &Resolver{r}
+}
+
+func (r *Resolver) Owner(ctx context.Context, obj *model.Emote) (*model.User, error) {
+	ownerID, err := primitive.ObjectIDFromHex(obj.OwnerID)
+	if err != nil || ownerID.IsZero() {
+		return nil, nil
+	}
+
+	user, err := helpers.FetchUser(r.Ctx, ctx, bson.M{"_id": ownerID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return helpers.UserStructureToModel(r.Ctx, user, helpers.FetchUserChannelSet(r.Ctx, ctx, user)), nil
+}
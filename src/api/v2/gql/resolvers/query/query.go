@@ -0,0 +1,274 @@
+package query
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/v2/generated"
+	"github.com/SevenTV/GQL/graph/v2/model"
+	"github.com/SevenTV/GQL/src/api/v2/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v2/gql/types"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const EMOTES_QUERY_LIMIT = 150
+
+type Resolver struct {
+	types.Resolver
+}
+
+func New(r types.Resolver) generated.QueryResolver {
+	return &Resolver{r}
+}
+
+// User finds a user by their ID, username or twitch ID
+func (r *Resolver) User(ctx context.Context, id string) (*model.User, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"username": strings.ToLower(id)},
+		bson.M{"connections.id": id},
+	}}
+	if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+		filter = bson.M{"_id": oid}
+	}
+
+	user, err := helpers.FetchUser(r.Ctx, ctx, filter)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownUser()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch user")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	return helpers.UserStructureToModel(r.Ctx, user, helpers.FetchUserChannelSet(r.Ctx, ctx, user)), nil
+}
+
+func (r *Resolver) Emote(ctx context.Context, id string) (*model.Emote, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.ErrBadObjectID()
+	}
+
+	emotes, err := helpers.FetchEmotesByVersionID(r.Ctx, ctx, []primitive.ObjectID{oid})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote")
+		return nil, errors.ErrInternalServerError()
+	}
+	if len(emotes) == 0 || !helpers.CanSeeEmote(ctx, emotes[0]) {
+		return nil, errors.ErrUnknownEmote()
+	}
+
+	return helpers.EmoteStructureToModel(r.Ctx, emotes[0], helpers.FetchGlobalEmoteSet(r.Ctx, ctx)), nil
+}
+
+func (r *Resolver) SearchEmotes(
+	ctx context.Context,
+	query string,
+	limitArg *int,
+	pageArg *int,
+	pageSizeArg *int,
+	globalState *string,
+	sortBy *string,
+	sortOrder *int,
+	channel *string,
+	submittedBy *string,
+	filter *model.EmoteFilter,
+) ([]*model.Emote, error) {
+	// Define limit (legacy clients may specify either limit or pageSize)
+	limit := 16
+	if pageSizeArg != nil {
+		limit = *pageSizeArg
+	} else if limitArg != nil {
+		limit = *limitArg
+	}
+	if limit > EMOTES_QUERY_LIMIT {
+		limit = EMOTES_QUERY_LIMIT
+	} else if limit < 1 {
+		return nil, errors.ErrInvalidRequest().SetDetail("limit cannot be less than 1")
+	}
+	page := 1
+	if pageArg != nil && *pageArg > 1 {
+		page = *pageArg
+	}
+
+	// Set up db query
+	query = strings.Trim(query, " ")
+	match := bson.M{
+		"versions.state.lifecycle": structures.EmoteLifecycleLive,
+		"$expr": bson.M{
+			"$gt": bson.A{bson.M{"$indexOfCP": bson.A{bson.M{"$toLower": "$name"}, strings.ToLower(query)}}, -1},
+		},
+	}
+	versionFilter := bson.M{}
+
+	// Filter by global state
+	globalSet := helpers.FetchGlobalEmoteSet(r.Ctx, ctx)
+	if globalState != nil && globalSet != nil {
+		ids := make([]primitive.ObjectID, len(globalSet.Emotes))
+		for i, ae := range globalSet.Emotes {
+			ids[i] = ae.ID
+		}
+		switch *globalState {
+		case "only":
+			versionFilter["$in"] = ids
+		case "hide":
+			versionFilter["$nin"] = ids
+		}
+	}
+
+	// Filter by channel
+	if channel != nil {
+		user, err := helpers.FetchUser(r.Ctx, ctx, bson.M{"username": strings.ToLower(*channel)})
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.ErrUnknownUser()
+			}
+			return nil, errors.ErrInternalServerError()
+		}
+
+		ids := []primitive.ObjectID{}
+		if set := helpers.FetchUserChannelSet(r.Ctx, ctx, user); set != nil {
+			for _, ae := range set.Emotes {
+				ids = append(ids, ae.ID)
+			}
+		}
+		if in, ok := versionFilter["$in"].([]primitive.ObjectID); ok {
+			ids = intersectIDs(in, ids)
+		}
+		versionFilter["$in"] = ids
+	} else {
+		// Unlisted emotes are only searchable within a channel
+		match["versions.state.listed"] = true
+	}
+	if len(versionFilter) > 0 {
+		match["versions.id"] = versionFilter
+	}
+
+	// Filter by owner
+	var ownerID *primitive.ObjectID
+	if submittedBy != nil {
+		user, err := helpers.FetchUser(r.Ctx, ctx, bson.M{"username": strings.ToLower(*submittedBy)})
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return []*model.Emote{}, nil
+			}
+			return nil, errors.ErrInternalServerError()
+		}
+		match["owner_id"] = user.ID
+		ownerID = &user.ID
+	}
+
+	// Filter by legacy visibility
+	var allSet, allClear structures.EmoteFlag
+	if filter != nil {
+		if filter.Visibility != nil {
+			allSet = visibilityToFlags(*filter.Visibility)
+		}
+		if filter.VisibilityClear != nil {
+			allClear = visibilityToFlags(*filter.VisibilityClear)
+		}
+	}
+	// Private emotes are only listed for those allowed to see them
+	if !auth.CanSeeHiddenEmotes(ctx) && (ownerID == nil || !auth.CanSeePrivateEmotesOf(ctx, *ownerID)) {
+		allClear |= structures.EmoteFlagsPrivate
+	}
+	if allSet != 0 || allClear != 0 {
+		flags := bson.M{}
+		if allSet != 0 {
+			flags["$bitsAllSet"] = allSet
+		}
+		if allClear != 0 {
+			flags["$bitsAllClear"] = allClear
+		}
+		match["flags"] = flags
+	}
+
+	// Define sorting
+	order := 1
+	if sortOrder != nil && *sortOrder == 1 {
+		order = -1
+	}
+	sortField := "versions.state.channel_count"
+	if sortBy != nil && *sortBy == "age" {
+		sortField = "_id"
+	}
+
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: sortField, Value: order}}}},
+		{{Key: "$skip", Value: (page - 1) * limit}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to search emotes")
+		return nil, errors.ErrInternalServerError()
+	}
+	emotes := []*structures.Emote{}
+	if err = cur.All(ctx, &emotes); err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emotes")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	result := make([]*model.Emote, len(emotes))
+	for i, e := range emotes {
+		helpers.BringForwardDefaultVersion(e)
+		result[i] = helpers.EmoteStructureToModel(r.Ctx, e, globalSet)
+	}
+	return result, nil
+}
+
+func (r *Resolver) Role(ctx context.Context, id string) (*model.Role, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.ErrBadObjectID()
+	}
+
+	roles, _ := r.Ctx.Inst().Query.Roles(ctx, bson.M{"_id": oid})
+	if len(roles) == 0 {
+		return nil, errors.ErrUnknownRole()
+	}
+	return helpers.RoleStructureToModel(r.Ctx, roles[0]), nil
+}
+
+func (r *Resolver) Roles(ctx context.Context) ([]*model.Role, error) {
+	roles, _ := r.Ctx.Inst().Query.Roles(ctx, bson.M{})
+
+	result := make([]*model.Role, len(roles))
+	for i, rol := range roles {
+		result[i] = helpers.RoleStructureToModel(r.Ctx, rol)
+	}
+	return result, nil
+}
+
+// visibilityToFlags translates legacy visibility bits to emote flags
+func visibilityToFlags(visibility int) structures.EmoteFlag {
+	var flags structures.EmoteFlag
+	if visibility&helpers.EmoteVisibilityPrivate != 0 {
+		flags |= structures.EmoteFlagsPrivate
+	}
+	if visibility&helpers.EmoteVisibilityZeroWidth != 0 {
+		flags |= structures.EmoteFlagsZeroWidth
+	}
+	return flags
+}
+
+func intersectIDs(a []primitive.ObjectID, b []primitive.ObjectID) []primitive.ObjectID {
+	m := make(map[primitive.ObjectID]bool, len(a))
+	for _, id := range a {
+		m[id] = true
+	}
+
+	result := []primitive.ObjectID{}
+	for _, id := range b {
+		if m[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package resolvers
+
+import (
+	"github.com/SevenTV/GQL/graph/v2/generated"
+
+	"github.com/SevenTV/GQL/src/api/v2/gql/resolvers/emote"
+	"github.com/SevenTV/GQL/src/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/GQL/src/api/v2/gql/resolvers/user"
+
+	"github.com/SevenTV/GQL/src/api/v2/gql/types"
+)
+
+type Resolver struct {
+	types.Resolver
+}
+
+func New(r types.Resolver) generated.ResolverRoot {
+	return &Resolver{
+		Resolver: r,
+	}
+}
+
+func (r *Resolver) Emote() generated.EmoteResolver {
+	return emote.New(r.Resolver)
+}
+
+func (r *Resolver) Query() generated.QueryResolver {
+	return query.New(r.Resolver)
+}
+
+func (r *Resolver) User() generated.UserResolver {
+	return user.New(r.Resolver)
+}
@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+
+	"github.com/SevenTV/GQL/graph/v2/generated"
+	"github.com/SevenTV/GQL/graph/v2/model"
+	"github.com/SevenTV/GQL/src/api/v2/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v2/gql/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Resolver struct {
+	types.Resolver
+}
+
+func New(r types.Resolver) generated.UserResolver {
+	return &Resolver{r}
+}
+
+// Emotes resolves the user's channel emotes
+func (r *Resolver) Emotes(ctx context.Context, obj *model.User) ([]*model.Emote, error) {
+	ids := []primitive.ObjectID{}
+	for _, v := range obj.EmoteIds {
+		if id, err := primitive.ObjectIDFromHex(v); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	emotes, err := helpers.FetchEmotesByVersionID(r.Ctx, ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	emotes = helpers.FilterVisibleEmotes(ctx, emotes)
+
+	globalSet := helpers.FetchGlobalEmoteSet(r.Ctx, ctx)
+	result := make([]*model.Emote, len(emotes))
+	for i, e := range emotes {
+		result[i] = helpers.EmoteStructureToModel(r.Ctx, e, globalSet)
+	}
+	return result, nil
+}
+
+// OwnedEmotes resolves the emotes created by the user, represented by their default version
+func (r *Resolver) OwnedEmotes(ctx context.Context, obj *model.User) ([]*model.Emote, error) {
+	userID, err := primitive.ObjectIDFromHex(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	emotes, err := r.Ctx.Inst().Query.Emotes(ctx, bson.M{"owner_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	emotes = helpers.FilterVisibleEmotes(ctx, emotes)
+
+	globalSet := helpers.FetchGlobalEmoteSet(r.Ctx, ctx)
+	result := make([]*model.Emote, len(emotes))
+	for i, e := range emotes {
+		helpers.BringForwardDefaultVersion(e)
+		result[i] = helpers.EmoteStructureToModel(r.Ctx, e, globalSet)
+	}
+	return result, nil
+}
+
+// Editors resolves the users allowed to edit this user's channel
+func (r *Resolver) Editors(ctx context.Context, obj *model.User) ([]*model.User, error) {
+	userID, err := primitive.ObjectIDFromHex(obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := helpers.FetchUser(r.Ctx, ctx, bson.M{"_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(user.Editors))
+	for i, ed := range user.Editors {
+		ids[i] = ed.ID
+	}
+
+	editors, err := helpers.FetchUsers(r.Ctx, ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.User, len(editors))
+	for i, u := range editors {
+		result[i] = helpers.UserStructureToModel(r.Ctx, u, nil)
+	}
+	return result, nil
+}
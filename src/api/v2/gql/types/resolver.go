@@ -0,0 +1,7 @@
+package types
+
+import "github.com/SevenTV/GQL/src/global"
+
+type Resolver struct {
+	Ctx global.Context
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var editorPermissions = map[model.UserEditorPermission]structures.UserEditorPermission{
+	model.UserEditorPermissionModifyEmotes:      structures.UserEditorPermissionModifyEmotes,
+	model.UserEditorPermissionUsePrivateEmotes:  structures.UserEditorPermissionUsePrivateEmotes,
+	model.UserEditorPermissionManageProfile:     structures.UserEditorPermissionManageProfile,
+	model.UserEditorPermissionManageOwnedEmotes: structures.UserEditorPermissionManageOwnedEmotes,
+	model.UserEditorPermissionManageEmoteSets:   structures.UserEditorPermissionManageEmoteSets,
+	model.UserEditorPermissionManageBilling:     structures.UserEditorPermissionManageBilling,
+	model.UserEditorPermissionManageEditors:     structures.UserEditorPermissionManageEditors,
+	model.UserEditorPermissionViewMessages:      structures.UserEditorPermissionViewMessages,
+}
+
+// CheckSelfOrEditor verifies that the actor may act on behalf of a user:
+// they must be that user, one of their editors granted the permission, or have the bypass role permission (if any)
+func CheckSelfOrEditor(ctx context.Context, gCtx global.Context, userID primitive.ObjectID, perm model.UserEditorPermission, bypass structures.RolePermission) error {
+	actor := For(ctx)
+	if actor == nil {
+		return errors.ErrUnauthorized()
+	}
+	if actor.ID == userID || (bypass != 0 && actor.HasPermission(bypass)) {
+		return nil
+	}
+
+	editables, err := gCtx.Inst().Query.UserEditorOf(ctx, actor.ID)
+	if err != nil {
+		logrus.WithError(err).Error("query, failed to fetch editables")
+		return errors.ErrInternalServerError()
+	}
+	for _, ed := range editables {
+		if ed.ID == userID && ed.HasPermission(editorPermissions[perm]) {
+			return nil
+		}
+	}
+
+	return errors.ErrInsufficientPrivilege().SetDetail("You must be this user or one of their editors with the %s permission", perm)
+}
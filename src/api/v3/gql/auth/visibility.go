@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CanSeeHiddenEmotes returns whether the actor may see private and unlisted emotes in listings
+func CanSeeHiddenEmotes(ctx context.Context) bool {
+	actor := For(ctx)
+	return actor != nil && actor.HasPermission(structures.RolePermissionBypassPrivacy)
+}
+
+// CanSeePrivateEmotesOf returns whether the actor may see the private emotes of a user:
+// they must be that user, one of their editors or be allowed to bypass privacy
+func CanSeePrivateEmotesOf(ctx context.Context, ownerID primitive.ObjectID) bool {
+	actor := For(ctx)
+	if actor == nil {
+		return false
+	}
+	if actor.ID == ownerID || actor.HasPermission(structures.RolePermissionBypassPrivacy) {
+		return true
+	}
+
+	owner, err := loaders.For(ctx).UserByID.Load(ownerID)
+	if err != nil || owner == nil {
+		return false
+	}
+	for _, ed := range owner.Editors {
+		if ed.ID == actor.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// CanSeeEmote returns whether the actor may see an emote. Unlisted emotes can be seen by anyone who knows their ID
+func CanSeeEmote(ctx context.Context, emote *model.Emote) bool {
+	if structures.EmoteFlag(emote.Flags)&structures.EmoteFlagsPrivate == 0 {
+		return true
+	}
+
+	return CanSeePrivateEmotesOf(ctx, emote.OwnerID)
+}
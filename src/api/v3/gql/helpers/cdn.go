@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/GQL/src/global"
+)
+
+const CDN_SIGNED_URL_TTL = time.Hour
+
+// CdnURL returns the URL of a file on the CDN. URLs are protocol-relative unless a scheme is configured
+func CdnURL(ctx global.Context, path string) string {
+	scheme := ctx.Config().Cdn.Scheme
+	if scheme != "" {
+		scheme += ":"
+	}
+
+	return fmt.Sprintf("%s//%s/%s", scheme, ctx.Config().CdnURL, path)
+}
+
+// SignedCdnURL returns the URL of a file on the CDN which only remains valid for a while, if a signing secret is configured
+func SignedCdnURL(ctx global.Context, path string) string {
+	return CdnURL(ctx, path) + CdnSignature(ctx, path)
+}
+
+// CdnSignature returns the query string which makes the URL of a file on the CDN valid for a while,
+// or nothing if no signing secret is configured.
+//
+// The signature is the hex encoded HMAC-SHA256 of the path and expiry ("/<path>?expires=<unix>").
+// Expiries are rounded so that the URL stays the same, and can be cached, for at least the configured duration
+func CdnSignature(ctx global.Context, path string) string {
+	cfg := ctx.Config().Cdn
+	if cfg.SigningSecret == "" {
+		return ""
+	}
+
+	ttl := CDN_SIGNED_URL_TTL
+	if cfg.SignedURLMinutes > 0 {
+		ttl = time.Duration(cfg.SignedURLMinutes) * time.Minute
+	}
+	step := int64(ttl.Seconds())
+	expires := (time.Now().Unix()/step + 2) * step
+
+	query := fmt.Sprintf("expires=%d", expires)
+	mac := hmac.New(sha256.New, []byte(cfg.SigningSecret))
+	mac.Write([]byte("/" + path + "?" + query))
+
+	return fmt.Sprintf("?%s&signature=%s", query, hex.EncodeToString(mac.Sum(nil)))
+}
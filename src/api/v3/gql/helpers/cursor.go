@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/GQL/graph/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CursorSortKey is the field which must be set on items of a paginated pipeline to the value they are sorted by.
+// It should never be null, so expressions which may be should be wrapped in $ifNull
+const CursorSortKey = "sort_key"
+
+// Cursor is an opaque position in a list, made up of the sort key and ID of the item at that position
+type Cursor struct {
+	Value bson.RawValue      `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// EncodeCursor creates a cursor pointing at the given item
+func EncodeCursor(item bson.Raw) (string, error) {
+	b, err := bson.Marshal(bson.M{
+		"v":  item.Lookup(CursorSortKey),
+		"id": item.Lookup("_id"),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor parses a cursor previously returned by EncodeCursor
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("bad cursor")
+	}
+
+	c := &Cursor{}
+	if err = bson.Unmarshal(b, c); err != nil || c.ID.IsZero() {
+		return nil, fmt.Errorf("bad cursor")
+	}
+	return c, nil
+}
+
+// CursorPipeline returns the stages selecting up to first items after the cursor.
+//
+// The items must have their CursorSortKey set and are ordered by it, then by ID.
+// One extra item is fetched to determine whether or not there is a next page, see CursorEdges
+func CursorPipeline(after *string, first int, order int32) (mongo.Pipeline, error) {
+	pipeline := mongo.Pipeline{}
+
+	if after != nil && *after != "" {
+		c, err := DecodeCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+
+		op := "$gt"
+		if order < 0 {
+			op = "$lt"
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{CursorSortKey: bson.M{op: c.Value}},
+			bson.M{CursorSortKey: c.Value, "_id": bson.M{op: c.ID}},
+		}}}})
+	}
+
+	return append(pipeline, []bson.D{
+		{{Key: "$sort", Value: bson.D{{Key: CursorSortKey, Value: order}, {Key: "_id", Value: order}}}},
+		{{Key: "$limit", Value: first + 1}},
+	}...), nil
+}
+
+// CursorEdges trims the extra item fetched by CursorPipeline, returning the cursors of the remaining items
+func CursorEdges(items []bson.Raw, first int, after *string) ([]bson.Raw, []string, *model.PageInfo, error) {
+	hasNext := len(items) > first
+	if hasNext {
+		items = items[:first]
+	}
+
+	cursors := make([]string, len(items))
+	for i, item := range items {
+		c, err := EncodeCursor(item)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		cursors[i] = c
+	}
+
+	info := &model.PageInfo{
+		HasNextPage:     hasNext,
+		HasPreviousPage: after != nil && *after != "",
+	}
+	if len(cursors) > 0 {
+		info.StartCursor = &cursors[0]
+		info.EndCursor = &cursors[len(cursors)-1]
+	}
+
+	return items, cursors, info, nil
+}
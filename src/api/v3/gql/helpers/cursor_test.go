@@ -0,0 +1,79 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{name: "int", value: int32(42)},
+		{name: "long", value: int64(1) << 40},
+		{name: "string", value: "peepoHappy"},
+		{name: "date", value: primitive.NewDateTimeFromTime(id.Timestamp())},
+		{name: "id", value: id},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := bson.Marshal(bson.M{"_id": id, CursorSortKey: tt.value, "name": "ignored"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := EncodeCursor(item)
+			if err != nil {
+				t.Fatalf("EncodeCursor() error = %v", err)
+			}
+			c, err := DecodeCursor(s)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+
+			if c.ID != id {
+				t.Errorf("ID = %s, want %s", c.ID.Hex(), id.Hex())
+			}
+			if want := bson.Raw(item).Lookup(CursorSortKey); !c.Value.Equal(want) {
+				t.Errorf("Value = %s, want %s", c.Value, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(v interface{}) string {
+		b, err := bson.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "empty", cursor: ""},
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte("abcd"))},
+		{name: "not bson", cursor: base64.RawURLEncoding.EncodeToString([]byte("peepoHappy"))},
+		{name: "no id", cursor: encode(bson.M{"v": 1})},
+		{name: "bad id", cursor: encode(bson.M{"v": 1, "id": "peepoHappy"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := DecodeCursor(tt.cursor); err == nil {
+				t.Errorf("DecodeCursor(%q) = %+v, want an error", tt.cursor, c)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmoteDeletion is the record kept of a deleted emote, allowing it to be restored
+type EmoteDeletion struct {
+	// The ID of the deleted emote
+	ID primitive.ObjectID `bson:"_id"`
+	// The user who deleted the emote
+	ActorID primitive.ObjectID `bson:"actor_id"`
+	Reason  string             `bson:"reason"`
+	// Whether or not the emote was deleted by a moderator rather than its owner or their editors
+	Moderated bool `bson:"moderated"`
+	// The lifecycle of each version prior to deletion
+	Lifecycles map[string]structures.EmoteLifecycle `bson:"lifecycles"`
+	// The emote sets the emote was removed from, and how it was named in them
+	EmoteSets []EmoteDeletionSet `bson:"emote_sets"`
+	DeletedAt time.Time          `bson:"deleted_at"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+}
+
+// EmoteDeletionSet is a version of a deleted emote which was active in a set
+type EmoteDeletionSet struct {
+	EmoteSetID primitive.ObjectID         `bson:"emote_set_id"`
+	ID         primitive.ObjectID         `bson:"id"`
+	Name       string                     `bson:"name"`
+	Flags      structures.ActiveEmoteFlag `bson:"flags"`
+}
+
+func EmoteDeletionToModel(s *EmoteDeletion) *model.DeletedEmote {
+	return &model.DeletedEmote{
+		ID:        s.ID,
+		ActorID:   s.ActorID,
+		Reason:    s.Reason,
+		Moderated: s.Moderated,
+		DeletedAt: s.DeletedAt,
+		ExpiresAt: s.ExpiresAt,
+	}
+}
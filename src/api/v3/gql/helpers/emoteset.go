@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevenTV/Common/redis"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const EMOTE_SET_MAX_SLOTS = 250
+
+// EmoteSetMaxSlots returns the number of slots the emote sets of a user with the given roles can have
+func EmoteSetMaxSlots(ctx global.Context, roleIDs []primitive.ObjectID) int {
+	cfg := ctx.Config().EmoteSets
+
+	max := EMOTE_SET_MAX_SLOTS
+	if cfg.MaxSlots > 0 {
+		max = cfg.MaxSlots
+	}
+	for _, id := range roleIDs {
+		if n := cfg.RoleMaxSlots[id.Hex()]; n > max {
+			max = n
+		}
+	}
+
+	return max
+}
+
+// RecordEmoteSetChanges counts emotes added to or removed from a set towards trending and stats, and clears the cache they affect
+func RecordEmoteSetChanges(ctx context.Context, gCtx global.Context, setID primitive.ObjectID, items []mutations.EmoteSetMutationSetEmoteItem) {
+	deltas := map[primitive.ObjectID]int{}
+	seen := map[primitive.ObjectID]bool{}
+	keys := []redis.Key{}
+	for _, it := range items {
+		if !seen[it.ID] {
+			seen[it.ID] = true
+
+			// Clear cache keys for active sets / channel count
+			k := fmt.Sprintf("emote:%s", it.ID.Hex())
+			keys = append(keys,
+				gCtx.Inst().Redis.ComposeKey("gql-v3", k+":active_sets"),
+				gCtx.Inst().Redis.ComposeKey("gql-v3", k+":channel_count"),
+			)
+			for _, p := range model.AllConnectionPlatform {
+				keys = append(keys, gCtx.Inst().Redis.ComposeKey("gql-v3", fmt.Sprintf("%s:channel_count:%s", k, p)))
+			}
+		}
+
+		switch it.Action {
+		case mutations.ListItemActionAdd:
+			deltas[it.ID]++
+		case mutations.ListItemActionRemove:
+			deltas[it.ID]--
+		}
+	}
+	if len(keys) > 0 {
+		_, _ = gCtx.Inst().Redis.Del(ctx, keys...)
+	}
+
+	// Count towards trending
+	changed := make([]primitive.ObjectID, 0, len(deltas))
+	for id, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		changed = append(changed, id)
+		logF := logrus.WithFields(logrus.Fields{
+			"emote_set_id": setID,
+			"emote_id":     id,
+		})
+		if err := gCtx.Inst().Trending.Record(ctx, id, delta); err != nil {
+			logF.WithError(err).Error("trending, failed to record emote set change")
+		}
+		if err := gCtx.Inst().Stats.RecordEmote(ctx, id, delta); err != nil {
+			logF.WithError(err).Error("stats, failed to record emote set change")
+		}
+	}
+
+	// The channel counts of changed emotes are searchable
+	if idx := gCtx.Inst().Search; idx != nil && len(changed) > 0 {
+		go idx.Refresh(gCtx, changed...)
+	}
+}
@@ -0,0 +1,189 @@
+package helpers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SevenTV/GQL/graph/model"
+)
+
+// The formats preferred when a client accepts several equally, most efficient first
+var imageFormatOrder = []model.ImageFormat{model.ImageFormatAvif, model.ImageFormatWebp, model.ImageFormatPng, model.ImageFormatGif}
+
+// Formats which any client can display, used when no image types are accepted explicitly
+var imageFormatFallback = []model.ImageFormat{model.ImageFormatPng, model.ImageFormatGif}
+
+var imageMediaTypes = map[string]model.ImageFormat{
+	"image/avif": model.ImageFormatAvif,
+	"image/webp": model.ImageFormatWebp,
+	"image/png":  model.ImageFormatPng,
+	"image/apng": model.ImageFormatPng,
+	"image/gif":  model.ImageFormatGif,
+}
+
+// ImageFilter selects among the images of an emote
+type ImageFilter struct {
+	Formats  []model.ImageFormat
+	Scales   []int
+	MinWidth *int
+	MaxWidth *int
+	// When set, only the first of these formats available at each scale is kept
+	Preferred []model.ImageFormat
+}
+
+// FilterImages returns the images matching the filter, in their original order
+func FilterImages(images []*model.Image, f ImageFilter) []*model.Image {
+	result := []*model.Image{}
+	for _, im := range images {
+		if len(f.Formats) > 0 && !containsFormat(f.Formats, im.Format) {
+			continue
+		}
+		if len(f.Scales) > 0 && !containsInt(f.Scales, im.Scale) {
+			continue
+		}
+		if f.MinWidth != nil && im.Width < *f.MinWidth {
+			continue
+		}
+		if f.MaxWidth != nil && im.Width > *f.MaxWidth {
+			continue
+		}
+
+		result = append(result, im)
+	}
+	if len(f.Preferred) == 0 {
+		return result
+	}
+
+	// Keep one format per scale, falling back to the most efficient available when none of the preferred are
+	order := append(append([]model.ImageFormat{}, f.Preferred...), imageFormatOrder...)
+	best := map[int]*model.Image{}
+	for _, im := range result {
+		if cur, ok := best[im.Scale]; !ok || formatRank(order, im.Format) < formatRank(order, cur.Format) {
+			best[im.Scale] = im
+		}
+	}
+
+	preferred := []*model.Image{}
+	for _, im := range result {
+		if best[im.Scale] == im {
+			preferred = append(preferred, im)
+		}
+	}
+	return preferred
+}
+
+// BestImage picks the image of a scale in the format a client most prefers, given the value of its Accept header
+func BestImage(images []*model.Image, scale int, accept string) *model.Image {
+	order := AcceptedImageFormats(accept)
+
+	var best *model.Image
+	for _, im := range images {
+		if im.Scale != scale || formatRank(order, im.Format) == len(order) {
+			continue
+		}
+		if best == nil || formatRank(order, im.Format) < formatRank(order, best.Format) {
+			best = im
+		}
+	}
+	return best
+}
+
+// AcceptedImageFormats returns the image formats a client accepts, most preferred first.
+//
+// Formats are ordered by their quality value, then by efficiency.
+// Wildcards aren't trusted as clients send them regardless of their support for newer formats: only PNG and GIF are assumed
+func AcceptedImageFormats(accept string) []model.ImageFormat {
+	q := map[model.ImageFormat]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		format, ok := imageMediaTypes[strings.ToLower(strings.TrimSpace(params[0]))]
+		if !ok {
+			continue
+		}
+
+		weight := 1.0
+		for _, p := range params[1:] {
+			if v := strings.TrimPrefix(strings.TrimSpace(p), "q="); v != p {
+				if w, err := strconv.ParseFloat(v, 64); err == nil {
+					weight = w
+				}
+			}
+		}
+		// A quality of zero refuses the format, which then isn't used as a fallback either
+		if cur, ok := q[format]; !ok || weight > cur {
+			q[format] = weight
+		}
+	}
+
+	result := []model.ImageFormat{}
+	for _, f := range imageFormatOrder {
+		if q[f] > 0 {
+			result = append(result, f)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return q[result[i]] > q[result[j]]
+	})
+
+	for _, f := range imageFormatFallback {
+		if _, ok := q[f]; !ok {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// BestImageFor picks the image for the client of a request, at scale 1 and with its Accept header unless specified
+func BestImageFor(ctx context.Context, images []*model.Image, scale *int, accept *string) *model.Image {
+	sc := 1
+	if scale != nil {
+		sc = *scale
+	}
+	a := AcceptFor(ctx)
+	if accept != nil {
+		a = *accept
+	}
+
+	return BestImage(images, sc, a)
+}
+
+// AcceptFor returns the Accept header of the request an operation is executed for
+func AcceptFor(ctx context.Context) string {
+	v, _ := ctx.Value(AcceptKey).(string)
+	return v
+}
+
+// ImageScale returns the scale of an emote file from its name, i.e "2x.webp"
+func ImageScale(name string) int {
+	i := strings.IndexByte(name, 'x')
+	if i < 1 {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(name[:i])
+	return n
+}
+
+func formatRank(order []model.ImageFormat, f model.ImageFormat) int {
+	for i, v := range order {
+		if v == f {
+			return i
+		}
+	}
+	return len(order)
+}
+
+func containsFormat(formats []model.ImageFormat, f model.ImageFormat) bool {
+	return formatRank(formats, f) < len(formats)
+}
+
+func containsInt(a []int, n int) bool {
+	for _, v := range a {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
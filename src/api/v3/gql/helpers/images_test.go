@@ -0,0 +1,142 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SevenTV/GQL/graph/model"
+)
+
+func TestAcceptedImageFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   []model.ImageFormat
+	}{
+		{
+			name:   "no header",
+			accept: "",
+			want:   []model.ImageFormat{model.ImageFormatPng, model.ImageFormatGif},
+		},
+		{
+			name:   "wildcards only",
+			accept: "image/*,*/*;q=0.8",
+			want:   []model.ImageFormat{model.ImageFormatPng, model.ImageFormatGif},
+		},
+		{
+			name:   "browser",
+			accept: "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8",
+			want:   []model.ImageFormat{model.ImageFormatAvif, model.ImageFormatWebp, model.ImageFormatPng, model.ImageFormatGif},
+		},
+		{
+			name:   "quality values",
+			accept: "image/avif;q=0.5, image/webp;q=0.9",
+			want:   []model.ImageFormat{model.ImageFormatWebp, model.ImageFormatAvif, model.ImageFormatPng, model.ImageFormatGif},
+		},
+		{
+			name:   "equal quality by efficiency",
+			accept: "image/gif, image/webp",
+			want:   []model.ImageFormat{model.ImageFormatWebp, model.ImageFormatGif, model.ImageFormatPng},
+		},
+		{
+			name:   "case and spacing",
+			accept: " IMAGE/WEBP ; q=1 ",
+			want:   []model.ImageFormat{model.ImageFormatWebp, model.ImageFormatPng, model.ImageFormatGif},
+		},
+		{
+			name:   "refused formats",
+			accept: "image/webp, image/png;q=0",
+			want:   []model.ImageFormat{model.ImageFormatWebp, model.ImageFormatGif},
+		},
+		{
+			name:   "highest quality of duplicates",
+			accept: "image/png;q=0.1, image/webp;q=0.5, image/apng",
+			want:   []model.ImageFormat{model.ImageFormatPng, model.ImageFormatWebp, model.ImageFormatGif},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AcceptedImageFormats(tt.accept); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AcceptedImageFormats(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterImages(t *testing.T) {
+	var images []*model.Image
+	for scale := 1; scale <= 4; scale++ {
+		for _, f := range []model.ImageFormat{model.ImageFormatWebp, model.ImageFormatAvif, model.ImageFormatPng} {
+			if f == model.ImageFormatAvif && scale == 4 {
+				continue // not available at every scale
+			}
+			images = append(images, &model.Image{Format: f, Scale: scale, Width: 32 * scale})
+		}
+	}
+	i := func(n int) *int { return &n }
+
+	type image struct {
+		format model.ImageFormat
+		scale  int
+	}
+	tests := []struct {
+		name   string
+		filter ImageFilter
+		want   []image
+	}{
+		{
+			name:   "formats",
+			filter: ImageFilter{Formats: []model.ImageFormat{model.ImageFormatAvif}},
+			want:   []image{{model.ImageFormatAvif, 1}, {model.ImageFormatAvif, 2}, {model.ImageFormatAvif, 3}},
+		},
+		{
+			name:   "scales",
+			filter: ImageFilter{Scales: []int{2}},
+			want:   []image{{model.ImageFormatWebp, 2}, {model.ImageFormatAvif, 2}, {model.ImageFormatPng, 2}},
+		},
+		{
+			name:   "width",
+			filter: ImageFilter{Formats: []model.ImageFormat{model.ImageFormatPng}, MinWidth: i(64), MaxWidth: i(96)},
+			want:   []image{{model.ImageFormatPng, 2}, {model.ImageFormatPng, 3}},
+		},
+		{
+			name:   "no match",
+			filter: ImageFilter{Scales: []int{5}},
+			want:   []image{},
+		},
+		{
+			name:   "preferred",
+			filter: ImageFilter{Preferred: []model.ImageFormat{model.ImageFormatWebp}},
+			want:   []image{{model.ImageFormatWebp, 1}, {model.ImageFormatWebp, 2}, {model.ImageFormatWebp, 3}, {model.ImageFormatWebp, 4}},
+		},
+		{
+			name:   "preferred falls back to the most efficient",
+			filter: ImageFilter{Preferred: []model.ImageFormat{model.ImageFormatGif}},
+			want:   []image{{model.ImageFormatAvif, 1}, {model.ImageFormatAvif, 2}, {model.ImageFormatAvif, 3}, {model.ImageFormatWebp, 4}},
+		},
+		{
+			name: "preferred among filtered",
+			filter: ImageFilter{
+				Formats:   []model.ImageFormat{model.ImageFormatPng, model.ImageFormatWebp},
+				Scales:    []int{1, 4},
+				Preferred: []model.ImageFormat{model.ImageFormatAvif, model.ImageFormatPng},
+			},
+			want: []image{{model.ImageFormatPng, 1}, {model.ImageFormatPng, 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FilterImages(images, tt.filter)
+
+			got := make([]image, len(result))
+			for i, im := range result {
+				got[i] = image{im.Format, im.Scale}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterImages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,4 +5,5 @@ import "github.com/SevenTV/Common/utils"
 const (
 	UserKey     = utils.Key("user")
 	PipelineKey = utils.Key("pipeline")
+	AcceptKey   = utils.Key("accept")
 )
@@ -0,0 +1,10 @@
+package helpers
+
+import "regexp"
+
+var (
+	// EmoteNameRegex matches valid emote names, which also applies to their aliases in emote sets
+	EmoteNameRegex = regexp.MustCompile(`^[-_A-Za-z():0-9]{2,100}$`)
+	// TagRegex matches valid emote and emote set tags
+	TagRegex = regexp.MustCompile(`^[0-9a-z]{3,30}$`)
+)
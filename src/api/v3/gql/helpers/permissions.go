@@ -0,0 +1,8 @@
+package helpers
+
+import "github.com/SevenTV/Common/structures/v3"
+
+// RolePermissionSchemaRead allows a user to introspect the schema outside of development
+//
+// This bit is reserved in GQL until it is part of Common's role permissions
+const RolePermissionSchemaRead structures.RolePermission = 1 << 57
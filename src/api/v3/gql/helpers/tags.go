@@ -0,0 +1,26 @@
+package helpers
+
+import (
+	"time"
+
+	"github.com/SevenTV/GQL/graph/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmoteTagBan is the record of a tag which may not be used on emotes
+type EmoteTagBan struct {
+	// The banned tag
+	Name     string             `bson:"_id"`
+	Reason   string             `bson:"reason"`
+	ActorID  primitive.ObjectID `bson:"actor_id"`
+	BannedAt time.Time          `bson:"banned_at"`
+}
+
+func EmoteTagBanToModel(s *EmoteTagBan) *model.BannedEmoteTag {
+	return &model.BannedEmoteTag{
+		Name:     s.Name,
+		Reason:   s.Reason,
+		ActorID:  s.ActorID,
+		BannedAt: s.BannedAt,
+	}
+}
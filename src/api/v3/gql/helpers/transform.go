@@ -10,6 +10,7 @@ import (
 	"github.com/SevenTV/GQL/graph/model"
 	"github.com/SevenTV/GQL/src/global"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var twitchPictureSizeRegExp = regexp.MustCompile("([0-9]{2,3})x([0-9]{2,3})")
@@ -37,7 +38,7 @@ func UserStructureToModel(ctx global.Context, s *structures.User) *model.User {
 
 	avatarURL := ""
 	if s.AvatarID != "" {
-		avatarURL = fmt.Sprintf("//%s/pp/%s/%s", ctx.Config().CdnURL, s.ID.Hex(), s.AvatarID)
+		avatarURL = CdnURL(ctx, fmt.Sprintf("pp/%s/%s", s.ID.Hex(), s.AvatarID))
 	} else {
 		for _, con := range s.Connections {
 			switch con.Platform {
@@ -149,15 +150,19 @@ func EmoteStructureToModel(ctx global.Context, s *structures.Emote) *model.Emote
 	versions := []*model.EmoteVersion{}
 	lifecycle := structures.EmoteLifecycleDisabled
 	animated := false
+	defaultID := primitive.NilObjectID
+	if len(s.Versions) > 0 {
+		defaultID = s.Versions[0].ID // the default version is kept first
+	}
 	for _, ver := range s.Versions {
 		vimages := []*model.Image{}
 		animated = ver.FrameCount > 1
-		if ver.State.Lifecycle < structures.EmoteLifecycleProcessing {
-			continue // skip if lifecycle isn't past pending
-		}
 		if ver.ID == s.ID {
 			lifecycle = ver.State.Lifecycle
 		}
+		if ver.State.Lifecycle < structures.EmoteLifecycleProcessing {
+			continue // skip if lifecycle isn't past pending
+		}
 		for _, f := range ver.Formats {
 			for _, im := range f.Files {
 				format := model.ImageFormatWebp
@@ -173,10 +178,15 @@ func EmoteStructureToModel(ctx global.Context, s *structures.Emote) *model.Emote
 					continue // skip if this is an animated emote version but image is static
 				}
 
-				// Set 3x as preview
-				url := fmt.Sprintf("//%s/emote/%s/%s", ctx.Config().CdnURL, ver.ID.Hex(), im.Name)
+				// Files of private emotes are only reachable through signed URLs
+				path := fmt.Sprintf("emote/%s/%s", ver.ID.Hex(), im.Name)
+				url := CdnURL(ctx, path)
+				if s.Flags&structures.EmoteFlagsPrivate != 0 {
+					url = SignedCdnURL(ctx, path)
+				}
 				img := &model.Image{
 					Name:     im.Name,
+					Scale:    ImageScale(im.Name),
 					Format:   format,
 					URL:      url,
 					Width:    int(im.Width),
@@ -191,7 +201,9 @@ func EmoteStructureToModel(ctx global.Context, s *structures.Emote) *model.Emote
 				}
 			}
 		}
-		versions = append(versions, EmoteVersionStructureToModel(ctx, ver, vimages))
+		v := EmoteVersionStructureToModel(ctx, ver, vimages)
+		v.Default = ver.ID == defaultID
+		versions = append(versions, v)
 	}
 
 	owner := structures.DeletedUser
@@ -215,6 +227,16 @@ func EmoteStructureToModel(ctx global.Context, s *structures.Emote) *model.Emote
 	}
 }
 
+// PrivateEmoteToModel returns what is shown in place of an emote which the viewer isn't allowed to see.
+// Nothing about the hidden emote, not even its ID, is carried over
+func PrivateEmoteToModel(ctx global.Context) *model.Emote {
+	e := EmoteStructureToModel(ctx, structures.DeletedEmote)
+	e.Name = "*PrivateEmote"
+	e.Flags = int(structures.EmoteFlagsPrivate)
+
+	return e
+}
+
 func EmoteStructureToPartialModel(ctx global.Context, m *model.Emote) *model.EmotePartial {
 	return &model.EmotePartial{
 		ID:        m.ID,
@@ -256,6 +278,7 @@ func EmoteSetStructureToModel(ctx global.Context, s *structures.EmoteSet) *model
 		Tags:       s.Tags,
 		Emotes:     emotes,
 		EmoteSlots: int(s.EmoteSlots),
+		Privileged: s.Privileged,
 		OwnerID:    &s.OwnerID,
 		Owner:      owner,
 	}
@@ -269,6 +292,7 @@ func EmoteVersionStructureToModel(ctx global.Context, s *structures.EmoteVersion
 		Timestamp:   s.ID.Timestamp(),
 		Images:      images,
 		Lifecycle:   int(s.State.Lifecycle),
+		Listed:      s.State.Listed,
 	}
 }
 
@@ -280,3 +304,40 @@ func ActiveEmoteStructureToModel(ctx global.Context, s *structures.ActiveEmote)
 		Timestamp: s.Timestamp,
 	}
 }
+
+func ReportStructureToModel(ctx global.Context, s *structures.Report) *model.Report {
+	notes := make([]string, len(s.Notes))
+	for i, n := range s.Notes {
+		notes[i] = n.Content
+	}
+	assignees := make([]*model.User, len(s.AssigneeIDs))
+	for i, id := range s.AssigneeIDs {
+		assignees[i] = &model.User{ID: id}
+	}
+
+	return &model.Report{
+		ID:         s.ID,
+		TargetKind: model.TargetKind(s.TargetKind),
+		TargetID:   s.TargetID,
+		Subject:    s.Subject,
+		Body:       s.Body,
+		Priority:   int(s.Priority),
+		Status:     model.ReportStatus(s.Status),
+		CreatedAt:  s.CreatedAt,
+		Notes:      notes,
+		Reporter:   &model.User{ID: s.ReporterID},
+		Assignees:  assignees,
+	}
+}
+
+func EmoteCommentStructureToModel(ctx global.Context, s *structures.Message) *model.EmoteComment {
+	d := structures.NewMessageBuilder(s).DecodeEmoteComment()
+
+	return &model.EmoteComment{
+		ID:        s.ID,
+		EmoteID:   d.EmoteID,
+		Author:    &model.User{ID: s.AuthorID},
+		Content:   d.Content,
+		CreatedAt: s.CreatedAt,
+	}
+}
@@ -0,0 +1,104 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/emotes"
+	"github.com/SevenTV/GQL/src/global"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EmoteVersionStates is what is stored about the versions of an emote beside the emote structure
+type EmoteVersionStates struct {
+	ID primitive.ObjectID `bson:"_id"`
+	// The version shown in search, which is also kept first in the list of versions.
+	// Emotes which predate this field default to their first version
+	DefaultVersionID primitive.ObjectID  `bson:"default_version_id"`
+	Versions         []EmoteVersionState `bson:"versions"`
+}
+
+type EmoteVersionState struct {
+	ID primitive.ObjectID `bson:"id"`
+	// Deprecated versions remain usable by the sets which have them, but cannot be made default
+	Deprecated bool `bson:"deprecated"`
+	// Where the version is in moderation. Versions which predate this field have no review state
+	Review emotes.ReviewState `bson:"review,omitempty"`
+	// The removed emote the version resembles, found when its images were hashed
+	DuplicateOf *primitive.ObjectID `bson:"duplicate_of,omitempty"`
+}
+
+// DefaultID returns the ID of the default version
+func (s *EmoteVersionStates) DefaultID() primitive.ObjectID {
+	if !s.DefaultVersionID.IsZero() {
+		return s.DefaultVersionID
+	}
+	if len(s.Versions) > 0 {
+		return s.Versions[0].ID
+	}
+	return primitive.NilObjectID
+}
+
+// IsDeprecated returns whether or not a version was deprecated
+func (s *EmoteVersionStates) IsDeprecated(versionID primitive.ObjectID) bool {
+	for _, v := range s.Versions {
+		if v.ID == versionID {
+			return v.Deprecated
+		}
+	}
+	return false
+}
+
+// ReviewOf returns the moderation state of a version
+func (s *EmoteVersionStates) ReviewOf(versionID primitive.ObjectID) emotes.ReviewState {
+	for _, v := range s.Versions {
+		if v.ID == versionID {
+			return v.Review
+		}
+	}
+	return ""
+}
+
+// Apply marks the default, deprecated and duplicate versions of an emote model
+func (s *EmoteVersionStates) Apply(m *model.Emote) {
+	defaultID := s.DefaultID()
+	for _, v := range m.Versions {
+		v.Default = v.ID == defaultID
+		v.Deprecated = s.IsDeprecated(v.ID)
+		for _, st := range s.Versions {
+			if st.ID == v.ID {
+				v.DuplicateOf = st.DuplicateOf
+			}
+		}
+	}
+}
+
+// FetchEmoteVersionStates retrieves the version states of the emotes matching a filter, keyed by the IDs of their versions
+func FetchEmoteVersionStates(ctx context.Context, gCtx global.Context, filter bson.M) (map[primitive.ObjectID]*EmoteVersionStates, error) {
+	cur, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, filter, options.Find().SetProjection(bson.M{
+		"default_version_id":    1,
+		"versions.id":           1,
+		"versions.deprecated":   1,
+		"versions.review":       1,
+		"versions.duplicate_of": 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*EmoteVersionStates{}
+	if err = cur.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	result := make(map[primitive.ObjectID]*EmoteVersionStates)
+	for _, s := range items {
+		for _, v := range s.Versions {
+			result[v.ID] = s
+		}
+	}
+	return result, nil
+}
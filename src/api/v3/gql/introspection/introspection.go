@@ -0,0 +1,52 @@
+package introspection
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Introspection enables schema reflection in development,
+// or for users who were granted permission to read the schema
+type Introspection struct {
+	gCtx global.Context
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = Introspection{}
+
+func New(gCtx global.Context) Introspection {
+	return Introspection{gCtx}
+}
+
+func (c Introspection) ExtensionName() string {
+	return "Introspection"
+}
+
+func (c Introspection) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (c Introspection) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	rc.DisableIntrospection = !Allowed(c.gCtx, auth.For(ctx))
+	return nil
+}
+
+// Allowed returns whether or not the given user may introspect the schema
+func Allowed(gCtx global.Context, user *structures.User) bool {
+	if gCtx.Config().IsDevelopment() {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+
+	return user.HasPermission(structures.RolePermissionSuperAdministrator) || user.HasPermission(helpers.RolePermissionSchemaRead)
+}
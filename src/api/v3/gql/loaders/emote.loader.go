@@ -9,6 +9,7 @@ import (
 	"github.com/SevenTV/GQL/graph/model"
 	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/global"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -44,19 +45,30 @@ func emoteByID(gCtx global.Context) *loaders.EmoteLoader {
 
 			if err == nil {
 				m := make(map[primitive.ObjectID]*structures.Emote)
+				ids := make([]primitive.ObjectID, 0, len(emotes))
 				for _, e := range emotes {
 					if e == nil {
 						continue
 					}
+					ids = append(ids, e.ID)
 					for _, ver := range e.Versions {
 						m[ver.ID] = e
 					}
 				}
 
+				// Mark default and deprecated versions, which the emote structure doesn't hold
+				states, err := helpers.FetchEmoteVersionStates(ctx, gCtx, bson.M{"_id": bson.M{"$in": ids}})
+				if err != nil {
+					logrus.WithError(err).Error("mongo, failed to fetch emote version states")
+				}
+
 				for i, v := range keys {
 					if x, ok := m[v]; ok {
 						x.ID = v
 						models[i] = helpers.EmoteStructureToModel(gCtx, x)
+						if s, ok := states[v]; ok {
+							s.Apply(models[i])
+						}
 					}
 				}
 			}
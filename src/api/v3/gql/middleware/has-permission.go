@@ -8,6 +8,7 @@ import (
 	"github.com/SevenTV/Common/structures/v3"
 	"github.com/SevenTV/GQL/graph/model"
 	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/global"
 )
 
@@ -20,46 +21,7 @@ func hasPermission(gCtx global.Context) func(ctx context.Context, obj interface{
 
 		var perms structures.RolePermission
 		for _, v := range role {
-			switch v {
-			case model.PermissionBypassPrivacy:
-				perms |= structures.RolePermissionBypassPrivacy
-			case model.PermissionEmotesetCreate:
-				perms |= structures.RolePermissionCreateEmoteSet
-			case model.PermissionEmotesetEdit:
-				perms |= structures.RolePermissionEditEmoteSet
-			case model.PermissionEmoteCreate:
-				perms |= structures.RolePermissionCreateEmote
-			case model.PermissionEmoteEditAny:
-				perms |= structures.RolePermissionEditAnyEmote
-			case model.PermissionEmotesetEditAny:
-				perms |= structures.RolePermissionEditAnyEmoteSet
-			case model.PermissionEmoteEdit:
-				perms |= structures.RolePermissionEditEmote
-			case model.PermissionFeatureProfilePictureAnimation:
-				perms |= structures.RolePermissionFeatureProfilePictureAnimation
-			case model.PermissionFeatureZerowidthEmoteType:
-				perms |= structures.RolePermissionFeatureZeroWidthEmoteType
-			case model.PermissionManageBans:
-				perms |= structures.RolePermissionManageBans
-			case model.PermissionManageCosmetics:
-				perms |= structures.RolePermissionManageCosmetics
-			case model.PermissionManageNews:
-				perms |= structures.RolePermissionManageNews
-			case model.PermissionManageReports:
-				perms |= structures.RolePermissionManageReports
-			case model.PermissionManageRoles:
-				perms |= structures.RolePermissionManageRoles
-			case model.PermissionManageStack:
-				perms |= structures.RolePermissionManageStack
-			case model.PermissionManageUsers:
-				perms |= structures.RolePermissionManageUsers
-			case model.PermissionReportCreate:
-				perms |= structures.RolePermissionReportCreate
-			case model.PermissionSendMessages:
-				perms |= structures.RolePermissionSendMessages
-			case model.PermissionSuperAdministrator:
-				perms |= structures.RolePermissionSuperAdministrator
-			}
+			perms |= rolePermission(v)
 		}
 
 		if !user.HasPermission(perms) {
@@ -69,3 +31,51 @@ func hasPermission(gCtx global.Context) func(ctx context.Context, obj interface{
 		return next(ctx)
 	}
 }
+
+// rolePermission returns the role permission bit of a schema permission
+func rolePermission(p model.Permission) structures.RolePermission {
+	switch p {
+	case model.PermissionBypassPrivacy:
+		return structures.RolePermissionBypassPrivacy
+	case model.PermissionEmotesetCreate:
+		return structures.RolePermissionCreateEmoteSet
+	case model.PermissionEmotesetEdit:
+		return structures.RolePermissionEditEmoteSet
+	case model.PermissionEmoteCreate:
+		return structures.RolePermissionCreateEmote
+	case model.PermissionEmoteEditAny:
+		return structures.RolePermissionEditAnyEmote
+	case model.PermissionEmotesetEditAny:
+		return structures.RolePermissionEditAnyEmoteSet
+	case model.PermissionEmoteEdit:
+		return structures.RolePermissionEditEmote
+	case model.PermissionFeatureProfilePictureAnimation:
+		return structures.RolePermissionFeatureProfilePictureAnimation
+	case model.PermissionFeatureZerowidthEmoteType:
+		return structures.RolePermissionFeatureZeroWidthEmoteType
+	case model.PermissionManageBans:
+		return structures.RolePermissionManageBans
+	case model.PermissionManageCosmetics:
+		return structures.RolePermissionManageCosmetics
+	case model.PermissionSchemaRead:
+		return helpers.RolePermissionSchemaRead
+	case model.PermissionManageNews:
+		return structures.RolePermissionManageNews
+	case model.PermissionManageReports:
+		return structures.RolePermissionManageReports
+	case model.PermissionManageRoles:
+		return structures.RolePermissionManageRoles
+	case model.PermissionManageStack:
+		return structures.RolePermissionManageStack
+	case model.PermissionManageUsers:
+		return structures.RolePermissionManageUsers
+	case model.PermissionReportCreate:
+		return structures.RolePermissionReportCreate
+	case model.PermissionSendMessages:
+		return structures.RolePermissionSendMessages
+	case model.PermissionSuperAdministrator:
+		return structures.RolePermissionSuperAdministrator
+	}
+
+	return 0
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func isSelfOrEditor(gCtx global.Context) func(ctx context.Context, obj interface{}, next graphql.Resolver, permission model.UserEditorPermission, bypass *model.Permission) (res interface{}, err error) {
+	return func(ctx context.Context, obj interface{}, next graphql.Resolver, permission model.UserEditorPermission, bypass *model.Permission) (res interface{}, err error) {
+		if auth.For(ctx) == nil {
+			return nil, errors.ErrUnauthorized()
+		}
+
+		// Find the user the field concerns
+		var userID primitive.ObjectID
+		switch o := obj.(type) {
+		case *model.UserOps:
+			userID = o.ID
+		case *model.EmoteSet:
+			if o.OwnerID != nil {
+				userID = *o.OwnerID
+			}
+		case *model.EmoteSetOps:
+			set := &structures.EmoteSet{}
+			if err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(ctx, bson.M{
+				"_id": o.ID,
+			}, options.FindOne().SetProjection(bson.M{"owner_id": 1})).Decode(set); err != nil {
+				if err == mongo.ErrNoDocuments {
+					return nil, errors.ErrUnknownEmoteSet()
+				}
+				logrus.WithError(err).Error("mongo, failed to fetch emote set")
+				return nil, errors.ErrInternalServerError()
+			}
+			userID = set.OwnerID
+		default:
+			logrus.Errorf("@isSelfOrEditor is not supported on fields of %T", obj)
+			return nil, errors.ErrInternalServerError()
+		}
+
+		var perm structures.RolePermission
+		if bypass != nil {
+			perm = rolePermission(*bypass)
+		}
+		if err := auth.CheckSelfOrEditor(ctx, gCtx, userID, permission, perm); err != nil {
+			return nil, err
+		}
+
+		return next(ctx)
+	}
+}
@@ -8,6 +8,7 @@ import (
 func New(ctx global.Context) generated.DirectiveRoot {
 	return generated.DirectiveRoot{
 		HasPermissions: hasPermission(ctx),
+		IsSelfOrEditor: isSelfOrEditor(ctx),
 		Internal:       internal(ctx),
 	}
 }
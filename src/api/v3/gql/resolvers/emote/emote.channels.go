@@ -14,6 +14,7 @@ import (
 	"github.com/SevenTV/Common/structures/v3/aggregations"
 	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
 	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/go-redis/redis/v8"
 	"github.com/hashicorp/go-multierror"
@@ -26,7 +27,7 @@ import (
 const EMOTE_CHANNEL_QUERY_SIZE_MOST = 50
 const EMOTE_CHANNEL_QUERY_PAGE_CAP = 500
 
-func (r *Resolver) Channels(ctx context.Context, obj *model.Emote, pageArg *int, limitArg *int) (*model.UserSearchResult, error) {
+func (r *Resolver) Channels(ctx context.Context, obj *model.Emote, pageArg *int, limitArg *int, platform *model.ConnectionPlatform, sortArg *model.Sort) (*model.UserSearchResult, error) {
 	limit := EMOTE_CHANNEL_QUERY_SIZE_MOST
 	if limitArg != nil {
 		limit = *limitArg
@@ -50,74 +51,40 @@ func (r *Resolver) Channels(ctx context.Context, obj *model.Emote, pageArg *int,
 		}).SetDetail("No further pagination is allowed")
 	}
 
-	// Fetch emote sets that have this emote
-	setIDs := []primitive.ObjectID{}
-
-	// Ping redis for a cached value
-	rKey := r.Ctx.Inst().Redis.ComposeKey("gql-v3", fmt.Sprintf("emote:%s:active_sets", obj.ID.Hex()))
-	v, err := r.Ctx.Inst().Redis.Get(ctx, rKey)
-	if err == nil && v != "" {
-		if err = json.Unmarshal(utils.S2B(v), &setIDs); err != nil {
-			logrus.WithError(err).Error("couldn't decode emote's active set ids")
-		}
-	} else {
-		cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).Find(ctx, bson.M{"emotes.id": obj.ID}, options.Find().SetProjection(bson.M{"owner_id": 1}))
-		if err != nil {
-			return nil, err
-		}
-		for i := 0; cur.Next(ctx); i++ {
-			v := &structures.EmoteSet{}
-			if err = cur.Decode(v); err != nil {
-				logrus.WithError(err).Error("mongo, couldn't decode into EmoteSet")
-			}
-			setIDs = append(setIDs, v.ID)
-		}
-
-		// Set in redis
-		b, err := json.Marshal(setIDs)
-		if err = multierror.Append(err, r.Ctx.Inst().Redis.SetEX(ctx, rKey, utils.B2S(b), time.Hour*6)).ErrorOrNil(); err != nil {
-			logrus.WithError(err).Error("failed to cache set ids in redis")
-		}
+	// The channels of a private emote are as private as the emote
+	if !auth.CanSeeEmote(ctx, obj) {
+		return &model.UserSearchResult{Items: []*model.User{}}, nil
 	}
 
-	// Fetch users with this set active
-	q := bson.M{
-		"connections.emote_set_id": bson.M{
-			"$in": setIDs,
-		},
+	channels, err := r.prepareChannels(ctx, obj, platform, sortArg)
+	if err != nil {
+		return nil, err
 	}
+
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	count := int64(0)
 	go func() { // Get the total channel count
 		defer wg.Done()
-		k := r.Ctx.Inst().Redis.ComposeKey("gql-v3", fmt.Sprintf("emote:%s:channel_count", obj.ID.Hex()))
+		name := fmt.Sprintf("emote:%s:channel_count", obj.ID.Hex())
+		if platform != nil {
+			name += ":" + platform.String()
+		}
+		k := r.Ctx.Inst().Redis.ComposeKey("gql-v3", name)
 
+		var err error
 		count, err = r.Ctx.Inst().Redis.RawClient().Get(ctx, k.String()).Int64()
 		if err == redis.Nil { // query if not cached
-			count, _ = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).CountDocuments(ctx, q)
+			count, _ = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).CountDocuments(ctx, channels.match)
 			_ = r.Ctx.Inst().Redis.SetEX(ctx, k, count, time.Hour*6)
 		}
 	}()
-	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Aggregate(ctx, aggregations.Combine(
+	cur, err := r.Ctx.Inst().Mongo.Collection(channels.collection).Aggregate(ctx, aggregations.Combine(
+		channels.pipeline,
 		mongo.Pipeline{
-			{{
-				Key:   "$match",
-				Value: q,
-			}},
-			{{
-				Key:   "$sort",
-				Value: bson.D{{Key: "metadata.role_position", Value: -1}},
-			}},
+			{{Key: "$sort", Value: bson.D{{Key: helpers.CursorSortKey, Value: channels.order}, {Key: "_id", Value: channels.order}}}},
 			{{Key: "$skip", Value: (page - 1) * limit}},
-			{{
-				Key:   "$limit",
-				Value: limit,
-			}},
-			{{
-				Key:   "$sort",
-				Value: bson.D{{Key: "metadata.role_position", Value: -1}, {Key: "username", Value: 1}},
-			}},
+			{{Key: "$limit", Value: limit}},
 		},
 		aggregations.UserRelationRoles,
 	))
@@ -144,3 +111,194 @@ func (r *Resolver) Channels(ctx context.Context, obj *model.Emote, pageArg *int,
 	}
 	return &results, nil
 }
+
+func (r *Resolver) ChannelsConnection(ctx context.Context, obj *model.Emote, firstArg *int, after *string, platform *model.ConnectionPlatform, sortArg *model.Sort) (*model.UserListConnection, error) {
+	first := EMOTE_CHANNEL_QUERY_SIZE_MOST
+	if firstArg != nil {
+		first = *firstArg
+	}
+	if first > EMOTE_CHANNEL_QUERY_SIZE_MOST {
+		first = EMOTE_CHANNEL_QUERY_SIZE_MOST
+	} else if first < 1 {
+		return nil, errors.ErrInvalidRequest().SetDetail("first cannot be less than 1")
+	}
+	if !auth.CanSeeEmote(ctx, obj) {
+		return &model.UserListConnection{
+			Edges:    []*model.UserListEdge{},
+			PageInfo: &model.PageInfo{},
+		}, nil
+	}
+
+	channels, err := r.prepareChannels(ctx, obj, platform, sortArg)
+	if err != nil {
+		return nil, err
+	}
+	page, err := helpers.CursorPipeline(after, first, channels.order)
+	if err != nil {
+		return nil, errors.ErrInvalidRequest().SetDetail(err.Error())
+	}
+
+	items := []bson.Raw{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(channels.collection).Aggregate(ctx, aggregations.Combine(
+		channels.pipeline,
+		page,
+		aggregations.UserRelationRoles,
+	))
+	if err == nil {
+		err = cur.All(ctx, &items)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote channels")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	items, cursors, info, err := helpers.CursorEdges(items, first, after)
+	if err != nil {
+		logrus.WithError(err).Error("failed to encode cursors")
+		return nil, errors.ErrInternalServerError()
+	}
+	edges := make([]*model.UserListEdge, 0, len(items))
+	for i, item := range items {
+		u := &structures.User{}
+		if err = bson.Unmarshal(item, u); err != nil {
+			logrus.WithError(err).Error("mongo, failed to decode user")
+			continue
+		}
+
+		edges = append(edges, &model.UserListEdge{
+			Cursor: cursors[i],
+			Node:   helpers.UserStructureToModel(r.Ctx, u),
+		})
+	}
+
+	return &model.UserListConnection{
+		Edges:    edges,
+		PageInfo: info,
+	}, nil
+}
+
+// emoteChannels is a prepared query for the users who have an emote, shared by the page and cursor based fields
+type emoteChannels struct {
+	// The filter on users, used for counting
+	match bson.M
+	// The collection the pipeline runs on and the stages producing users with their sort key set
+	collection mongo.CollectionName
+	pipeline   mongo.Pipeline
+	order      int32
+}
+
+// prepareChannels builds the query for the users who have the emote in a set, optionally only through a connection to a platform.
+//
+// Users are sorted by their highest role, or by when the emote was added to their set ("added_at")
+func (r *Resolver) prepareChannels(ctx context.Context, obj *model.Emote, platform *model.ConnectionPlatform, sortArg *model.Sort) (*emoteChannels, error) {
+	setIDs := r.activeSetIDs(ctx, obj.ID)
+
+	c := &emoteChannels{
+		match:      bson.M{"connections.emote_set_id": bson.M{"$in": setIDs}},
+		collection: mongo.CollectionNameUsers,
+		order:      -1,
+	}
+	if platform != nil {
+		c.match = bson.M{"connections": bson.M{"$elemMatch": bson.M{
+			"platform":     platform.String(),
+			"emote_set_id": bson.M{"$in": setIDs},
+		}}}
+	}
+
+	sortBy := "role"
+	if sortArg != nil {
+		sortBy = sortArg.Value
+		if sortArg.Order == model.SortOrderAscending {
+			c.order = 1
+		}
+	}
+
+	switch sortBy {
+	case "role":
+		c.pipeline = mongo.Pipeline{
+			{{Key: "$match", Value: c.match}},
+			{{Key: "$addFields", Value: bson.M{helpers.CursorSortKey: bson.M{"$ifNull": bson.A{"$metadata.role_position", 0}}}}},
+		}
+	case "added_at":
+		// Start from the sets, which hold the time the emote was added, and find who they belong to
+		cond := bson.A{bson.M{"$eq": bson.A{"$$c.emote_set_id", "$_id"}}}
+		if platform != nil {
+			cond = append(cond, bson.M{"$eq": bson.A{"$$c.platform", platform.String()}})
+		}
+
+		c.collection = mongo.CollectionNameEmoteSets
+		c.pipeline = mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": setIDs}}}},
+			{{Key: "$project", Value: bson.M{"emote": bson.M{"$filter": bson.M{
+				"input": "$emotes",
+				"as":    "e",
+				"cond":  bson.M{"$eq": bson.A{"$$e.id", obj.ID}},
+			}}}}},
+			{{Key: "$lookup", Value: bson.M{
+				"from":         mongo.CollectionNameUsers,
+				"localField":   "_id",
+				"foreignField": "connections.emote_set_id",
+				"as":           "user",
+			}}},
+			{{Key: "$unwind", Value: "$user"}},
+			{{Key: "$match", Value: bson.M{"$expr": bson.M{"$gt": bson.A{
+				bson.M{"$size": bson.M{"$filter": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$user.connections", bson.A{}}},
+					"as":    "c",
+					"cond":  bson.M{"$and": cond},
+				}}},
+				0,
+			}}}}},
+			// A user may have the emote through several connections, the earliest addition counts
+			{{Key: "$group", Value: bson.M{
+				"_id":      "$user._id",
+				"user":     bson.M{"$first": "$user"},
+				"added_at": bson.M{"$min": bson.M{"$arrayElemAt": bson.A{"$emote.timestamp", 0}}},
+			}}},
+			{{Key: "$replaceRoot", Value: bson.M{"newRoot": bson.M{"$mergeObjects": bson.A{
+				"$user",
+				bson.M{helpers.CursorSortKey: bson.M{"$ifNull": bson.A{"$added_at", bson.M{"$toDate": "$_id"}}}},
+			}}}}},
+		}
+	default:
+		return nil, errors.ErrInvalidRequest().SetDetail("Unknown sort: %s", sortBy)
+	}
+
+	return c, nil
+}
+
+// activeSetIDs returns the IDs of the emote sets which have the emote
+func (r *Resolver) activeSetIDs(ctx context.Context, emoteID primitive.ObjectID) []primitive.ObjectID {
+	setIDs := []primitive.ObjectID{}
+
+	// Ping redis for a cached value
+	rKey := r.Ctx.Inst().Redis.ComposeKey("gql-v3", fmt.Sprintf("emote:%s:active_sets", emoteID.Hex()))
+	v, err := r.Ctx.Inst().Redis.Get(ctx, rKey)
+	if err == nil && v != "" {
+		if err = json.Unmarshal(utils.S2B(v), &setIDs); err != nil {
+			logrus.WithError(err).Error("couldn't decode emote's active set ids")
+		}
+		return setIDs
+	}
+
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).Find(ctx, bson.M{"emotes.id": emoteID}, options.Find().SetProjection(bson.M{"owner_id": 1}))
+	if err != nil {
+		logrus.WithError(err).Error("mongo, couldn't fetch emote's active sets")
+		return setIDs
+	}
+	for i := 0; cur.Next(ctx); i++ {
+		v := &structures.EmoteSet{}
+		if err = cur.Decode(v); err != nil {
+			logrus.WithError(err).Error("mongo, couldn't decode into EmoteSet")
+		}
+		setIDs = append(setIDs, v.ID)
+	}
+
+	// Set in redis
+	b, err := json.Marshal(setIDs)
+	if err = multierror.Append(err, r.Ctx.Inst().Redis.SetEX(ctx, rKey, utils.B2S(b), time.Hour*6)).ErrorOrNil(); err != nil {
+		logrus.WithError(err).Error("failed to cache set ids in redis")
+	}
+
+	return setIDs
+}
@@ -0,0 +1,87 @@
+package emote
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/generated"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/api/v3/gql/types"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const EMOTE_COMMENTS_QUERY_LIMIT = 100
+
+func (r *Resolver) Comments(ctx context.Context, obj *model.Emote, after *primitive.ObjectID, limitArg *int) ([]*model.EmoteComment, error) {
+	if !auth.CanSeeEmote(ctx, obj) {
+		return []*model.EmoteComment{}, nil
+	}
+
+	limit := 20
+	if limitArg != nil {
+		limit = *limitArg
+	}
+	if limit > EMOTE_COMMENTS_QUERY_LIMIT {
+		limit = EMOTE_COMMENTS_QUERY_LIMIT
+	} else if limit < 1 {
+		return nil, errors.ErrInvalidRequest().SetDetail("limit cannot be less than 1")
+	}
+
+	// Comments are attached to the emote's ID, which is that of its first version
+	ids := []primitive.ObjectID{obj.ID}
+	for _, ver := range obj.Versions {
+		if ver.ID != obj.ID {
+			ids = append(ids, ver.ID)
+		}
+	}
+
+	filter := bson.M{
+		"kind":          structures.MessageKindEmoteComment,
+		"data.emote_id": bson.M{"$in": ids},
+	}
+	if after != nil {
+		filter["_id"] = bson.M{"$lt": after}
+	}
+
+	messages := []*structures.Message{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameMessages).Find(ctx, filter, options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetLimit(int64(limit)),
+	)
+	if err == nil {
+		err = cur.All(ctx, &messages)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote comments")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	result := make([]*model.EmoteComment, len(messages))
+	for i, msg := range messages {
+		result[i] = helpers.EmoteCommentStructureToModel(r.Ctx, msg)
+	}
+	return result, nil
+}
+
+type ResolverComment struct {
+	types.Resolver
+}
+
+func NewComment(r types.Resolver) generated.EmoteCommentResolver {
+	return &ResolverComment{r}
+}
+
+func (r *ResolverComment) Author(ctx context.Context, obj *model.EmoteComment) (*model.User, error) {
+	if obj.Author.ID.IsZero() {
+		return helpers.UserStructureToModel(r.Ctx, structures.DeletedUser), nil
+	}
+	return loaders.For(ctx).UserByID.Load(obj.Author.ID)
+}
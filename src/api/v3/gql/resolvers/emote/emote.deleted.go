@@ -0,0 +1,22 @@
+package emote
+
+import (
+	"context"
+
+	"github.com/SevenTV/GQL/graph/generated"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/api/v3/gql/types"
+)
+
+type ResolverDeleted struct {
+	types.Resolver
+}
+
+func NewDeleted(r types.Resolver) generated.DeletedEmoteResolver {
+	return &ResolverDeleted{r}
+}
+
+func (r *ResolverDeleted) Emote(ctx context.Context, obj *model.DeletedEmote) (*model.Emote, error) {
+	return loaders.For(ctx).EmoteByID.Load(obj.ID)
+}
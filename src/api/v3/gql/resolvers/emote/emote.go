@@ -7,6 +7,7 @@ import (
 	"github.com/SevenTV/Common/structures/v3"
 	"github.com/SevenTV/GQL/graph/generated"
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	"github.com/SevenTV/GQL/src/api/v3/gql/types"
 	"github.com/sirupsen/logrus"
@@ -21,23 +22,22 @@ func New(r types.Resolver) generated.EmoteResolver {
 	return &Resolver{r}
 }
 
-func (r *Resolver) Images(ctx context.Context, obj *model.Emote, format []model.ImageFormat) ([]*model.Image, error) {
-	result := []*model.Image{}
-	for _, im := range obj.Images {
-		ok := len(format) == 0
-		if !ok {
-			for _, f := range format {
-				if im.Format == f {
-					result = append(result, im)
-				}
-			}
-			continue
-		}
+func (r *Resolver) Images(ctx context.Context, obj *model.Emote, formats []model.ImageFormat, scales []int, minWidth *int, maxWidth *int, preferred []model.ImageFormat) ([]*model.Image, error) {
+	return helpers.FilterImages(obj.Images, helpers.ImageFilter{
+		Formats:   formats,
+		Scales:    scales,
+		MinWidth:  minWidth,
+		MaxWidth:  maxWidth,
+		Preferred: preferred,
+	}), nil
+}
 
-		result = append(result, im)
-	}
+func (r *Resolver) BestImage(ctx context.Context, obj *model.Emote, scale *int, accept *string) (*model.Image, error) {
+	return helpers.BestImageFor(ctx, obj.Images, scale, accept), nil
+}
 
-	return result, nil
+func (r *Resolver) Versions(ctx context.Context, obj *model.Emote) ([]*model.EmoteVersion, error) {
+	return versionsOf(ctx, obj.ID, obj.Versions)
 }
 
 func (r *Resolver) Owner(ctx context.Context, obj *model.Emote) (*model.User, error) {
@@ -6,6 +6,7 @@ import (
 	"github.com/SevenTV/Common/structures/v3"
 	"github.com/SevenTV/GQL/graph/generated"
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	"github.com/SevenTV/GQL/src/api/v3/gql/types"
 )
@@ -18,23 +19,22 @@ func NewPartial(r types.Resolver) generated.EmotePartialResolver {
 	return &ResolverPartial{r}
 }
 
-func (r *ResolverPartial) Images(ctx context.Context, obj *model.EmotePartial, format []model.ImageFormat) ([]*model.Image, error) {
-	result := []*model.Image{}
-	for _, im := range obj.Images {
-		ok := len(format) == 0
-		if !ok {
-			for _, f := range format {
-				if im.Format == f {
-					result = append(result, im)
-				}
-			}
-			continue
-		}
-
-		result = append(result, im)
-	}
+func (r *ResolverPartial) Images(ctx context.Context, obj *model.EmotePartial, formats []model.ImageFormat, scales []int, minWidth *int, maxWidth *int, preferred []model.ImageFormat) ([]*model.Image, error) {
+	return helpers.FilterImages(obj.Images, helpers.ImageFilter{
+		Formats:   formats,
+		Scales:    scales,
+		MinWidth:  minWidth,
+		MaxWidth:  maxWidth,
+		Preferred: preferred,
+	}), nil
+}
+
+func (r *ResolverPartial) BestImage(ctx context.Context, obj *model.EmotePartial, scale *int, accept *string) (*model.Image, error) {
+	return helpers.BestImageFor(ctx, obj.Images, scale, accept), nil
+}
 
-	return result, nil
+func (r *ResolverPartial) Versions(ctx context.Context, obj *model.EmotePartial) ([]*model.EmoteVersion, error) {
+	return versionsOf(ctx, obj.ID, obj.Versions)
 }
 
 func (r *ResolverPartial) Owner(ctx context.Context, obj *model.EmotePartial) (*model.User, error) {
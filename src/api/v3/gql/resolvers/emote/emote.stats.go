@@ -0,0 +1,95 @@
+package emote
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/SevenTV/GQL/src/stats"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EMOTE_STATS_RANGE_DEFAULT = 30
+	EMOTE_STATS_RANGE_MAX     = 365
+)
+
+func (r *Resolver) Stats(ctx context.Context, obj *model.Emote, rangeArg *int) ([]*model.EmoteStatsPoint, error) {
+	if !auth.CanSeeEmote(ctx, obj) {
+		return []*model.EmoteStatsPoint{}, nil
+	}
+
+	days := EMOTE_STATS_RANGE_DEFAULT
+	if rangeArg != nil {
+		days = *rangeArg
+	}
+	if days < 1 || days > EMOTE_STATS_RANGE_MAX {
+		return nil, errors.ErrInvalidRequest().SetDetail("range must be between 1 and %d", EMOTE_STATS_RANGE_MAX)
+	}
+
+	// Stats are recorded per version, alongside the markers of the days counts were taken on
+	ids := []primitive.ObjectID{obj.ID, stats.SNAPSHOT_MARKER_ID}
+	for _, ver := range obj.Versions {
+		if ver.ID != obj.ID {
+			ids = append(ids, ver.ID)
+		}
+	}
+
+	today := stats.Day(time.Now())
+	since := today.AddDate(0, 0, 1-days)
+
+	rows := []struct {
+		Date         time.Time `bson:"_id"`
+		ChannelCount int       `bson:"channel_count"`
+		Adds         int       `bson:"adds"`
+		Removes      int       `bson:"removes"`
+		Snapshot     bool      `bson:"snapshot"`
+	}{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteStats).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"emote_id": bson.M{"$in": ids},
+			"date":     bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           "$date",
+			"channel_count": bson.M{"$sum": "$channel_count"},
+			"adds":          bson.M{"$sum": "$adds"},
+			"removes":       bson.M{"$sum": "$removes"},
+			"snapshot":      bson.M{"$max": bson.M{"$eq": bson.A{"$emote_id", stats.SNAPSHOT_MARKER_ID}}},
+		}}},
+	})
+	if err == nil {
+		err = cur.All(ctx, &rows)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote stats")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	// Days without a record had no usage. The channel count of days on which no snapshot was taken is unknown
+	points := make([]*model.EmoteStatsPoint, days)
+	for i := range points {
+		points[i] = &model.EmoteStatsPoint{Date: since.AddDate(0, 0, i)}
+	}
+	for _, row := range rows {
+		i := int(row.Date.Sub(since).Hours() / 24)
+		if i < 0 || i >= days {
+			continue
+		}
+
+		if row.Snapshot {
+			count := row.ChannelCount
+			points[i].ChannelCount = &count
+		}
+		points[i].Adds = row.Adds
+		points[i].Removes = row.Removes
+	}
+
+	return points, nil
+}
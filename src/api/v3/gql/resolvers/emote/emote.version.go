@@ -5,7 +5,10 @@ import (
 
 	"github.com/SevenTV/GQL/graph/generated"
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	"github.com/SevenTV/GQL/src/api/v3/gql/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ResolverVersion struct {
@@ -16,21 +19,29 @@ func NewVersion(r types.Resolver) generated.EmoteVersionResolver {
 	return &ResolverVersion{r}
 }
 
-func (r *ResolverVersion) Images(ctx context.Context, obj *model.EmoteVersion, format []model.ImageFormat) ([]*model.Image, error) {
-	result := []*model.Image{}
-	for _, im := range obj.Images {
-		ok := len(format) == 0
-		if !ok {
-			for _, f := range format {
-				if im.Format == f {
-					result = append(result, im)
-				}
-			}
-			continue
-		}
+func (r *ResolverVersion) Images(ctx context.Context, obj *model.EmoteVersion, formats []model.ImageFormat, scales []int, minWidth *int, maxWidth *int, preferred []model.ImageFormat) ([]*model.Image, error) {
+	return helpers.FilterImages(obj.Images, helpers.ImageFilter{
+		Formats:   formats,
+		Scales:    scales,
+		MinWidth:  minWidth,
+		MaxWidth:  maxWidth,
+		Preferred: preferred,
+	}), nil
+}
 
-		result = append(result, im)
-	}
+func (r *ResolverVersion) BestImage(ctx context.Context, obj *model.EmoteVersion, scale *int, accept *string) (*model.Image, error) {
+	return helpers.BestImageFor(ctx, obj.Images, scale, accept), nil
+}
 
-	return result, nil
+// versionsOf returns the versions of an emote as loaded by ID, which are the ones marked as default or deprecated.
+// Emotes transformed elsewhere keep their own versions if they cannot be loaded
+func versionsOf(ctx context.Context, id primitive.ObjectID, fallback []*model.EmoteVersion) ([]*model.EmoteVersion, error) {
+	emote, err := loaders.For(ctx).EmoteByID.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if emote == nil || emote.ID != id {
+		return fallback, nil
+	}
+	return emote.Versions, nil
 }
@@ -0,0 +1,219 @@
+package emoteset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EMOTE_SET_BULK_OPS_MAX = 500
+	// How many times operations are validated again when the set is modified before they are written
+	EMOTE_SET_BULK_ATTEMPTS = 3
+)
+
+func (r *ResolverOps) BulkEmotes(ctx context.Context, obj *model.EmoteSetOps, ops []*model.EmoteSetOpInput) ([]*model.EmoteSetOpResult, error) {
+	actor := auth.For(ctx)
+	if actor == nil {
+		return nil, errors.ErrUnauthorized()
+	}
+	if len(ops) > EMOTE_SET_BULK_OPS_MAX {
+		return nil, errors.ErrInvalidRequest().SetDetail("Too many operations (max %d)", EMOTE_SET_BULK_OPS_MAX)
+	}
+
+	// Fetch the emotes being added
+	addIDs := []primitive.ObjectID{}
+	for _, op := range ops {
+		if op.Action == model.ListItemActionAdd {
+			addIDs = append(addIDs, op.ID)
+		}
+	}
+	emotes := map[primitive.ObjectID]*model.Emote{}
+	if len(addIDs) > 0 {
+		found, _ := loaders.For(ctx).EmoteByID.LoadAll(addIDs)
+		for i, e := range found {
+			if e != nil && e.ID == addIDs[i] {
+				emotes[e.ID] = e
+			}
+		}
+	}
+
+	// The changes are only written if the set is still as it was validated against, otherwise they are validated again
+	for attempt := 1; ; attempt++ {
+		set := &structures.EmoteSet{}
+		if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(ctx, bson.M{
+			"_id": obj.ID,
+		}).Decode(set); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.ErrUnknownEmoteSet()
+			}
+			logrus.WithError(err).Error("mongo, failed to fetch emote set")
+			return nil, errors.ErrInternalServerError()
+		}
+
+		// Validate every operation against the set as it would be after the previous ones
+		sim := newEmoteSetSim(set)
+		results := make([]*model.EmoteSetOpResult, len(ops))
+		items := make([]mutations.EmoteSetMutationSetEmoteItem, len(ops))
+		valid := true
+		for i, op := range ops {
+			name, err := sim.apply(ctx, op, emotes[op.ID])
+
+			results[i] = &model.EmoteSetOpResult{
+				ID:     op.ID,
+				Action: op.Action,
+				Name:   name,
+			}
+			if err != nil {
+				msg := err.Error()
+				results[i].Error = &msg
+				valid = false
+			}
+			items[i] = mutations.EmoteSetMutationSetEmoteItem{
+				Action: mutations.ListItemAction(op.Action),
+				ID:     op.ID,
+				Name:   name,
+			}
+		}
+		if !valid || len(items) == 0 {
+			return results, nil
+		}
+
+		// Apply all changes in a single write
+		written, err := r.writeEmoteChanges(ctx, actor, set, sim, items)
+		if err != nil {
+			return nil, err
+		}
+		if !written {
+			if attempt < EMOTE_SET_BULK_ATTEMPTS {
+				continue
+			}
+			return nil, errors.ErrInvalidRequest().SetDetail("The emote set is being modified, try again")
+		}
+
+		for _, res := range results {
+			res.Applied = true
+		}
+		return results, nil
+	}
+}
+
+// emoteSetSim tracks the emotes of a set while operations are validated
+type emoteSetSim struct {
+	slots  int
+	active map[primitive.ObjectID]string
+	names  map[string]primitive.ObjectID
+	// Emotes which were already changed by an operation
+	changed map[primitive.ObjectID]bool
+	// Emotes which were added, in order
+	added []primitive.ObjectID
+}
+
+func newEmoteSetSim(set *structures.EmoteSet) *emoteSetSim {
+	sim := &emoteSetSim{
+		slots:   int(set.EmoteSlots),
+		active:  map[primitive.ObjectID]string{},
+		names:   map[string]primitive.ObjectID{},
+		changed: map[primitive.ObjectID]bool{},
+	}
+	for _, ae := range set.Emotes {
+		sim.active[ae.ID] = ae.Name
+		sim.names[ae.Name] = ae.ID
+	}
+
+	return sim
+}
+
+// apply validates an operation and applies it to the simulated set, returning the name of the emote in the set
+func (sim *emoteSetSim) apply(ctx context.Context, op *model.EmoteSetOpInput, emote *model.Emote) (string, error) {
+	name := sim.active[op.ID]
+	if op.Name != nil {
+		name = *op.Name
+	}
+
+	if sim.changed[op.ID] {
+		return name, fmt.Errorf("the emote is changed by an earlier operation")
+	}
+	sim.changed[op.ID] = true
+
+	_, isActive := sim.active[op.ID]
+	switch op.Action {
+	case model.ListItemActionAdd:
+		if emote == nil || emote.Lifecycle != int(structures.EmoteLifecycleLive) || !auth.CanSeeEmote(ctx, emote) {
+			return name, fmt.Errorf("unknown emote")
+		}
+		if op.Name == nil {
+			name = emote.Name
+		}
+		if isActive {
+			return name, fmt.Errorf("the emote is already in the set")
+		}
+		if sim.slots > 0 && len(sim.active) >= sim.slots {
+			return name, fmt.Errorf("no emote slots left (max %d)", sim.slots)
+		}
+	case model.ListItemActionUpdate:
+		if !isActive {
+			return name, fmt.Errorf("the emote is not in the set")
+		}
+		if op.Name == nil {
+			return name, fmt.Errorf("a name is required to update the emote")
+		}
+	case model.ListItemActionRemove:
+		if !isActive {
+			return name, fmt.Errorf("the emote is not in the set")
+		}
+
+		name = sim.active[op.ID]
+		delete(sim.names, name)
+		delete(sim.active, op.ID)
+		return name, nil
+	}
+
+	if !helpers.EmoteNameRegex.MatchString(name) {
+		return name, fmt.Errorf("bad emote name: %s", name)
+	}
+	if id, ok := sim.names[name]; ok && id != op.ID {
+		return name, fmt.Errorf("an emote named %s is already in the set", name)
+	}
+
+	delete(sim.names, sim.active[op.ID])
+	sim.active[op.ID] = name
+	sim.names[name] = op.ID
+	if !isActive {
+		sim.added = append(sim.added, op.ID)
+	}
+	return name, nil
+}
+
+// emotes returns the emotes of the simulated set: those of the set which remain, renamed if so, then those added
+func (sim *emoteSetSim) emotes(set *structures.EmoteSet, now time.Time) []*structures.ActiveEmote {
+	result := make([]*structures.ActiveEmote, 0, len(sim.active))
+	for _, ae := range set.Emotes {
+		if name, ok := sim.active[ae.ID]; ok {
+			e := *ae
+			e.Name = name
+			result = append(result, &e)
+		}
+	}
+	for _, id := range sim.added {
+		result = append(result, &structures.ActiveEmote{
+			ID:        id,
+			Name:      sim.active[id],
+			Timestamp: now,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,250 @@
+package emoteset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEmoteSetSimApply(t *testing.T) {
+	existing, other, added := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	live := &model.Emote{ID: added, Name: "peepoHappy", Lifecycle: int(structures.EmoteLifecycleLive)}
+	private := &model.Emote{ID: added, Name: "peepoHappy", Lifecycle: int(structures.EmoteLifecycleLive), Flags: int(structures.EmoteFlagsPrivate)}
+	pending := &model.Emote{ID: added, Name: "peepoHappy", Lifecycle: int(structures.EmoteLifecycleProcessing)}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name  string
+		slots int32
+		ops   []*model.EmoteSetOpInput
+		emote *model.Emote
+		// The name returned by the last operation, and whether it fails
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "add with the emote's name",
+			ops:   []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionAdd}},
+			emote: live,
+			want:  "peepoHappy",
+		},
+		{
+			name:  "add with an alias",
+			ops:   []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionAdd, Name: str("happy")}},
+			emote: live,
+			want:  "happy",
+		},
+		{
+			name:    "add an emote which isn't live",
+			ops:     []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionAdd}},
+			emote:   pending,
+			wantErr: true,
+		},
+		{
+			name:    "add a private emote without an actor",
+			ops:     []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionAdd}},
+			emote:   private,
+			wantErr: true,
+		},
+		{
+			name:    "add an emote already in the set",
+			ops:     []*model.EmoteSetOpInput{{ID: existing, Action: model.ListItemActionAdd}},
+			emote:   &model.Emote{ID: existing, Name: "Kappa", Lifecycle: int(structures.EmoteLifecycleLive)},
+			want:    "Kappa",
+			wantErr: true,
+		},
+		{
+			name:    "add with a taken name",
+			ops:     []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionAdd, Name: str("Kappa")}},
+			emote:   live,
+			want:    "Kappa",
+			wantErr: true,
+		},
+		{
+			name:    "add with a bad name",
+			ops:     []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionAdd, Name: str("no spaces")}},
+			emote:   live,
+			want:    "no spaces",
+			wantErr: true,
+		},
+		{
+			name:    "add without slots left",
+			slots:   2,
+			ops:     []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionAdd}},
+			emote:   live,
+			want:    "peepoHappy",
+			wantErr: true,
+		},
+		{
+			name:  "add into the slot of a removed emote",
+			slots: 2,
+			ops: []*model.EmoteSetOpInput{
+				{ID: other, Action: model.ListItemActionRemove},
+				{ID: added, Action: model.ListItemActionAdd},
+			},
+			emote: live,
+			want:  "peepoHappy",
+		},
+		{
+			name: "take the name of a removed emote",
+			ops: []*model.EmoteSetOpInput{
+				{ID: existing, Action: model.ListItemActionRemove},
+				{ID: added, Action: model.ListItemActionAdd, Name: str("Kappa")},
+			},
+			emote: live,
+			want:  "Kappa",
+		},
+		{
+			name: "take the name of a renamed emote",
+			ops: []*model.EmoteSetOpInput{
+				{ID: existing, Action: model.ListItemActionUpdate, Name: str("Keepo")},
+				{ID: other, Action: model.ListItemActionUpdate, Name: str("Kappa")},
+			},
+			want: "Kappa",
+		},
+		{
+			name: "rename",
+			ops:  []*model.EmoteSetOpInput{{ID: existing, Action: model.ListItemActionUpdate, Name: str("Keepo")}},
+			want: "Keepo",
+		},
+		{
+			name:    "rename without a name",
+			ops:     []*model.EmoteSetOpInput{{ID: existing, Action: model.ListItemActionUpdate}},
+			want:    "Kappa",
+			wantErr: true,
+		},
+		{
+			name:    "rename an emote not in the set",
+			ops:     []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionUpdate, Name: str("Keepo")}},
+			want:    "Keepo",
+			wantErr: true,
+		},
+		{
+			name: "remove",
+			ops:  []*model.EmoteSetOpInput{{ID: other, Action: model.ListItemActionRemove}},
+			want: "PogChamp",
+		},
+		{
+			name:    "remove an emote not in the set",
+			ops:     []*model.EmoteSetOpInput{{ID: added, Action: model.ListItemActionRemove}},
+			wantErr: true,
+		},
+		{
+			name: "change an emote twice",
+			ops: []*model.EmoteSetOpInput{
+				{ID: existing, Action: model.ListItemActionUpdate, Name: str("Keepo")},
+				{ID: existing, Action: model.ListItemActionRemove},
+			},
+			want:    "Keepo",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newEmoteSetSim(&structures.EmoteSet{
+				EmoteSlots: tt.slots,
+				Emotes: []*structures.ActiveEmote{
+					{ID: existing, Name: "Kappa"},
+					{ID: other, Name: "PogChamp"},
+				},
+			})
+
+			var (
+				name string
+				err  error
+			)
+			for i, op := range tt.ops {
+				name, err = sim.apply(context.Background(), op, tt.emote)
+				if i < len(tt.ops)-1 && err != nil {
+					t.Fatalf("operation %d: unexpected error: %v", i, err)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error: %v", err, tt.wantErr)
+			}
+			if name != tt.want {
+				t.Errorf("name = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmoteSetSimEmotes(t *testing.T) {
+	a, b, c, d := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	then, now := time.Unix(1600000000, 0), time.Unix(1700000000, 0)
+	str := func(s string) *string { return &s }
+	emoteOf := func(id primitive.ObjectID, name string) *model.Emote {
+		return &model.Emote{ID: id, Name: name, Lifecycle: int(structures.EmoteLifecycleLive)}
+	}
+
+	type op struct {
+		input *model.EmoteSetOpInput
+		emote *model.Emote
+	}
+	tests := []struct {
+		name string
+		ops  []op
+		want []structures.ActiveEmote
+	}{
+		{
+			name: "unchanged",
+			want: []structures.ActiveEmote{{ID: a, Name: "Kappa", Timestamp: then}, {ID: b, Name: "PogChamp", Timestamp: then}},
+		},
+		{
+			name: "renamed in place",
+			ops:  []op{{input: &model.EmoteSetOpInput{ID: a, Action: model.ListItemActionUpdate, Name: str("Keepo")}}},
+			want: []structures.ActiveEmote{{ID: a, Name: "Keepo", Timestamp: then}, {ID: b, Name: "PogChamp", Timestamp: then}},
+		},
+		{
+			name: "removed",
+			ops:  []op{{input: &model.EmoteSetOpInput{ID: a, Action: model.ListItemActionRemove}}},
+			want: []structures.ActiveEmote{{ID: b, Name: "PogChamp", Timestamp: then}},
+		},
+		{
+			name: "added last, in order",
+			ops: []op{
+				{input: &model.EmoteSetOpInput{ID: d, Action: model.ListItemActionAdd}, emote: emoteOf(d, "EZ")},
+				{input: &model.EmoteSetOpInput{ID: a, Action: model.ListItemActionRemove}},
+				{input: &model.EmoteSetOpInput{ID: c, Action: model.ListItemActionAdd, Name: str("Kappa")}, emote: emoteOf(c, "KappaPride")},
+			},
+			want: []structures.ActiveEmote{
+				{ID: b, Name: "PogChamp", Timestamp: then},
+				{ID: d, Name: "EZ", Timestamp: now},
+				{ID: c, Name: "Kappa", Timestamp: now},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := &structures.EmoteSet{Emotes: []*structures.ActiveEmote{
+				{ID: a, Name: "Kappa", Timestamp: then},
+				{ID: b, Name: "PogChamp", Timestamp: then},
+			}}
+			sim := newEmoteSetSim(set)
+			for i, o := range tt.ops {
+				if _, err := sim.apply(context.Background(), o.input, o.emote); err != nil {
+					t.Fatalf("operation %d: unexpected error: %v", i, err)
+				}
+			}
+
+			got := sim.emotes(set, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d emotes, want %d", len(got), len(tt.want))
+			}
+			for i, ae := range got {
+				if *ae != tt.want[i] {
+					t.Errorf("emote %d = %+v, want %+v", i, *ae, tt.want[i])
+				}
+			}
+			if set.Emotes[0].Name != "Kappa" {
+				t.Errorf("the set was modified")
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/SevenTV/GQL/graph/generated"
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	"github.com/SevenTV/GQL/src/api/v3/gql/types"
 )
@@ -17,6 +19,20 @@ func New(r types.Resolver) generated.EmoteSetResolver {
 	return &Resolver{r}
 }
 
+func (r *Resolver) Emotes(ctx context.Context, obj *model.EmoteSet) ([]*model.ActiveEmote, error) {
+	result := make([]*model.ActiveEmote, len(obj.Emotes))
+	for i, ae := range obj.Emotes {
+		result[i] = ae
+		if ae.Emote != nil && !auth.CanSeeEmote(ctx, ae.Emote) {
+			placeholder := *ae
+			placeholder.Emote = helpers.PrivateEmoteToModel(r.Ctx)
+			result[i] = &placeholder
+		}
+	}
+
+	return result, nil
+}
+
 func (r *Resolver) Owner(ctx context.Context, obj *model.EmoteSet) (*model.User, error) {
 	if obj.OwnerID == nil {
 		return nil, nil
@@ -0,0 +1,379 @@
+package emoteset
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const EMOTE_SET_HISTORY_QUERY_LIMIT = 100
+
+type emoteSetHistoryEntry struct {
+	ID         primitive.ObjectID          `bson:"_id"`
+	EmoteSetID primitive.ObjectID          `bson:"emote_set_id"`
+	ActorID    primitive.ObjectID          `bson:"actor_id"`
+	Action     model.EmoteSetHistoryAction `bson:"action"`
+	EmoteID    primitive.ObjectID          `bson:"emote_id,omitempty"`
+	// The name of the emote in the set, or that of the set when it is edited
+	Name string `bson:"name"`
+	// The name of the emote before it was renamed
+	OldName   string    `bson:"old_name,omitempty"`
+	Timestamp time.Time `bson:"timestamp"`
+}
+
+func (e *emoteSetHistoryEntry) toModel() *model.EmoteSetHistoryEntry {
+	m := &model.EmoteSetHistoryEntry{
+		ID:        e.ID,
+		Action:    e.Action,
+		ActorID:   e.ActorID,
+		Name:      e.Name,
+		Timestamp: e.Timestamp,
+	}
+	if !e.EmoteID.IsZero() {
+		m.EmoteID = &e.EmoteID
+	}
+	if e.OldName != "" {
+		m.OldName = &e.OldName
+	}
+
+	return m
+}
+
+// History: changes made to the set, latest first
+func (r *Resolver) History(ctx context.Context, obj *model.EmoteSet, after *primitive.ObjectID, limitArg *int) ([]*model.EmoteSetHistoryEntry, error) {
+	limit := 20
+	if limitArg != nil {
+		limit = *limitArg
+	}
+	if limit > EMOTE_SET_HISTORY_QUERY_LIMIT {
+		limit = EMOTE_SET_HISTORY_QUERY_LIMIT
+	} else if limit < 1 {
+		return nil, errors.ErrInvalidRequest().SetDetail("limit cannot be less than 1")
+	}
+
+	filter := bson.M{"emote_set_id": obj.ID}
+	if after != nil {
+		filter["_id"] = bson.M{"$lt": after}
+	}
+
+	entries := []*emoteSetHistoryEntry{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteSetHistory).Find(ctx, filter, options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetLimit(int64(limit)),
+	)
+	if err == nil {
+		err = cur.All(ctx, &entries)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote set history")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	result := make([]*model.EmoteSetHistoryEntry, len(entries))
+	for i, e := range entries {
+		result[i] = e.toModel()
+	}
+	return result, nil
+}
+
+// RevertTo: restore the emotes of a set as they were right after a change, as far as its slots allow
+func (r *ResolverOps) RevertTo(ctx context.Context, obj *model.EmoteSetOps, historyID primitive.ObjectID) (*model.EmoteSetImportResult, error) {
+	actor := auth.For(ctx)
+	if actor == nil {
+		return nil, errors.ErrUnauthorized()
+	}
+
+	set, err := r.fetchManagedEmoteSet(ctx, actor, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.fetchHistoryEntry(ctx, set.ID, historyID); err != nil {
+		return nil, err
+	}
+
+	// Walk back through the later changes to find what the set looked like
+	later := []*emoteSetHistoryEntry{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteSetHistory).Find(ctx, bson.M{
+		"emote_set_id": set.ID,
+		"_id":          bson.M{"$gt": historyID},
+	}, options.Find().SetSort(bson.M{"_id": -1}))
+	if err == nil {
+		err = cur.All(ctx, &later)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote set history")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	wanted := activeEmoteNames(set)
+	restoreIDs := []primitive.ObjectID{}
+	for _, e := range later {
+		switch e.Action {
+		case model.EmoteSetHistoryActionAdd:
+			delete(wanted, e.EmoteID)
+		case model.EmoteSetHistoryActionRemove:
+			wanted[e.EmoteID] = e.Name
+			restoreIDs = append(restoreIDs, e.EmoteID)
+		case model.EmoteSetHistoryActionUpdate:
+			wanted[e.EmoteID] = e.OldName
+		}
+	}
+
+	result := &model.EmoteSetImportResult{
+		Conflicts: []*model.EmoteSetImportConflict{},
+		Skipped:   []*model.EmoteSetImportSkip{},
+	}
+	skip := func(id primitive.ObjectID, reason model.EmoteSetImportSkipReason) {
+		result.Skipped = append(result.Skipped, &model.EmoteSetImportSkip{
+			ID:     id,
+			Name:   wanted[id],
+			Reason: reason,
+		})
+	}
+
+	sim := newEmoteSetSim(set)
+	items := []mutations.EmoteSetMutationSetEmoteItem{}
+	apply := func(op *model.EmoteSetOpInput, emote *model.Emote) error {
+		name, err := sim.apply(ctx, op, emote)
+		if err == nil {
+			items = append(items, mutations.EmoteSetMutationSetEmoteItem{
+				Action: mutations.ListItemAction(op.Action),
+				ID:     op.ID,
+				Name:   name,
+			})
+		}
+		return err
+	}
+
+	// Remove the emotes added since, freeing their slots and names
+	renames := []primitive.ObjectID{}
+	for _, ae := range set.Emotes {
+		name, ok := wanted[ae.ID]
+		if !ok {
+			_ = apply(&model.EmoteSetOpInput{ID: ae.ID, Action: model.ListItemActionRemove}, nil)
+		} else if name != ae.Name {
+			renames = append(renames, ae.ID)
+		}
+	}
+
+	// Rename the emotes whose name is free, until no more can be
+	for progress := true; progress && len(renames) > 0; {
+		progress = false
+		pending := []primitive.ObjectID{}
+		for _, id := range renames {
+			name := wanted[id]
+			if other, ok := sim.names[name]; ok && other != id {
+				pending = append(pending, id)
+				continue
+			}
+			if err := apply(&model.EmoteSetOpInput{ID: id, Action: model.ListItemActionUpdate, Name: &name}, nil); err != nil {
+				skip(id, model.EmoteSetImportSkipReasonConflict)
+				continue
+			}
+			progress = true
+		}
+		renames = pending
+	}
+	for _, id := range renames {
+		skip(id, model.EmoteSetImportSkipReasonConflict)
+	}
+
+	// Add back the removed emotes, the latest removed first
+	seen := map[primitive.ObjectID]bool{}
+	addIDs := []primitive.ObjectID{}
+	for _, id := range restoreIDs {
+		if _, ok := wanted[id]; !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, ok := sim.active[id]; !ok {
+			addIDs = append(addIDs, id)
+		}
+	}
+	emotes := []*model.Emote{}
+	if len(addIDs) > 0 {
+		emotes, _ = loaders.For(ctx).EmoteByID.LoadAll(addIDs)
+	}
+	for i, id := range addIDs {
+		name := wanted[id]
+		switch e := emotes[i]; {
+		case e == nil || e.ID != id:
+			skip(id, model.EmoteSetImportSkipReasonUnavailable)
+		case !auth.CanSeeEmote(ctx, e):
+			skip(id, model.EmoteSetImportSkipReasonPrivate)
+		case sim.slots > 0 && len(sim.active) >= sim.slots:
+			skip(id, model.EmoteSetImportSkipReasonNoSlots)
+		default:
+			if other, ok := sim.names[name]; ok && other != id {
+				skip(id, model.EmoteSetImportSkipReasonConflict)
+				continue
+			}
+			if err := apply(&model.EmoteSetOpInput{ID: id, Action: model.ListItemActionAdd, Name: &name}, e); err != nil {
+				skip(id, model.EmoteSetImportSkipReasonUnavailable)
+				continue
+			}
+			result.Added++
+		}
+	}
+
+	if len(items) > 0 {
+		if err := r.applyEmoteChanges(ctx, actor, set, items); err != nil {
+			return nil, err
+		}
+		loaders.For(ctx).EmoteSetByID.Clear(set.ID)
+	}
+
+	result.EmoteSet, err = loaders.For(ctx).EmoteSetByID.Load(set.ID)
+	return result, err
+}
+
+// Undo: reverse a single change made to the emotes of a set
+func (r *ResolverOps) Undo(ctx context.Context, obj *model.EmoteSetOps, historyID primitive.ObjectID) (*model.EmoteSet, error) {
+	actor := auth.For(ctx)
+	if actor == nil {
+		return nil, errors.ErrUnauthorized()
+	}
+
+	set, err := r.fetchManagedEmoteSet(ctx, actor, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := r.fetchHistoryEntry(ctx, set.ID, historyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		op    *model.EmoteSetOpInput
+		emote *model.Emote
+	)
+	switch entry.Action {
+	case model.EmoteSetHistoryActionAdd:
+		op = &model.EmoteSetOpInput{ID: entry.EmoteID, Action: model.ListItemActionRemove}
+	case model.EmoteSetHistoryActionRemove:
+		op = &model.EmoteSetOpInput{ID: entry.EmoteID, Action: model.ListItemActionAdd, Name: &entry.Name}
+		if emote, err = loaders.For(ctx).EmoteByID.Load(entry.EmoteID); err != nil || emote == nil || emote.ID != entry.EmoteID {
+			return nil, errors.ErrUnknownEmote()
+		}
+	case model.EmoteSetHistoryActionUpdate:
+		op = &model.EmoteSetOpInput{ID: entry.EmoteID, Action: model.ListItemActionUpdate, Name: &entry.OldName}
+	default:
+		return nil, errors.ErrInvalidRequest().SetDetail("Only changes to the emotes of a set can be undone")
+	}
+
+	name, err := newEmoteSetSim(set).apply(ctx, op, emote)
+	if err != nil {
+		return nil, errors.ErrInvalidRequest().SetDetail("Cannot undo this change: %s", err.Error())
+	}
+	if err = r.applyEmoteChanges(ctx, actor, set, []mutations.EmoteSetMutationSetEmoteItem{{
+		Action: mutations.ListItemAction(op.Action),
+		ID:     op.ID,
+		Name:   name,
+	}}); err != nil {
+		return nil, err
+	}
+
+	loaders.For(ctx).EmoteSetByID.Clear(set.ID)
+	return loaders.For(ctx).EmoteSetByID.Load(set.ID)
+}
+
+// fetchHistoryEntry retrieves a change made to an emote set
+func (r *ResolverOps) fetchHistoryEntry(ctx context.Context, setID primitive.ObjectID, id primitive.ObjectID) (*emoteSetHistoryEntry, error) {
+	entry := &emoteSetHistoryEntry{}
+	if err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteSetHistory).FindOne(ctx, bson.M{
+		"_id":          id,
+		"emote_set_id": setID,
+	}).Decode(entry); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrInvalidRequest().SetDetail("Unknown history entry")
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote set history entry")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	return entry, nil
+}
+
+// recordHistory adds an entry to the history of a set for each change made to its emotes
+func (r *ResolverOps) recordHistory(ctx context.Context, actor *structures.User, set *structures.EmoteSet, before map[primitive.ObjectID]string, items []mutations.EmoteSetMutationSetEmoteItem) {
+	after := activeEmoteNames(set)
+	names := make(map[primitive.ObjectID]string, len(before))
+	for id, name := range before {
+		names[id] = name
+	}
+
+	now := time.Now()
+	entries := make([]interface{}, 0, len(items))
+	for _, it := range items {
+		e := &emoteSetHistoryEntry{
+			ID:         primitive.NewObjectID(),
+			EmoteSetID: set.ID,
+			ActorID:    actor.ID,
+			EmoteID:    it.ID,
+			Name:       it.Name,
+			Timestamp:  now,
+		}
+		switch it.Action {
+		case mutations.ListItemActionAdd:
+			e.Action = model.EmoteSetHistoryActionAdd
+			if e.Name == "" {
+				e.Name = after[it.ID] // the emote was added under its own name
+			}
+			names[it.ID] = e.Name
+		case mutations.ListItemActionRemove:
+			e.Action = model.EmoteSetHistoryActionRemove
+			e.Name = names[it.ID]
+			delete(names, it.ID)
+		case mutations.ListItemActionUpdate:
+			e.Action = model.EmoteSetHistoryActionUpdate
+			e.OldName = names[it.ID]
+			names[it.ID] = e.Name
+		}
+		entries = append(entries, e)
+	}
+
+	r.insertHistory(ctx, entries)
+}
+
+// recordSetEdit adds an entry to the history of a set for a change made to the set itself
+func (r *ResolverOps) recordSetEdit(ctx context.Context, actor *structures.User, set *structures.EmoteSet) {
+	r.insertHistory(ctx, []interface{}{&emoteSetHistoryEntry{
+		ID:         primitive.NewObjectID(),
+		EmoteSetID: set.ID,
+		ActorID:    actor.ID,
+		Action:     model.EmoteSetHistoryActionEdit,
+		Name:       set.Name,
+		Timestamp:  time.Now(),
+	}})
+}
+
+func (r *ResolverOps) insertHistory(ctx context.Context, entries []interface{}) {
+	if len(entries) == 0 {
+		return
+	}
+	if _, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteSetHistory).InsertMany(ctx, entries); err != nil {
+		logrus.WithError(err).Error("mongo, failed to record emote set history")
+	}
+}
+
+// activeEmoteNames returns the names of the emotes in a set
+func activeEmoteNames(set *structures.EmoteSet) map[primitive.ObjectID]string {
+	names := make(map[primitive.ObjectID]string, len(set.Emotes))
+	for _, ae := range set.Emotes {
+		names[ae.ID] = ae.Name
+	}
+
+	return names
+}
@@ -0,0 +1,142 @@
+package emoteset
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportFrom: copy the emotes of another set into this one, resolving name conflicts with the given strategy
+func (r *ResolverOps) ImportFrom(ctx context.Context, obj *model.EmoteSetOps, sourceID primitive.ObjectID, strategyArg *model.EmoteSetImportStrategy) (*model.EmoteSetImportResult, error) {
+	actor := auth.For(ctx)
+	if actor == nil {
+		return nil, errors.ErrUnauthorized()
+	}
+	strategy := model.EmoteSetImportStrategyMerge
+	if strategyArg != nil {
+		strategy = *strategyArg
+	}
+	if sourceID == obj.ID {
+		return nil, errors.ErrDontBeSilly().SetDetail("Cannot import a set into itself")
+	}
+
+	target, err := r.fetchManagedEmoteSet(ctx, actor, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := loaders.For(ctx).EmoteSetByID.Load(sourceID)
+	if err != nil || source == nil {
+		return nil, errors.ErrUnknownEmoteSet()
+	}
+
+	result := &model.EmoteSetImportResult{
+		Conflicts: []*model.EmoteSetImportConflict{},
+		Skipped:   []*model.EmoteSetImportSkip{},
+	}
+	skip := func(ae *model.ActiveEmote, reason model.EmoteSetImportSkipReason) {
+		result.Skipped = append(result.Skipped, &model.EmoteSetImportSkip{
+			ID:     ae.ID,
+			Name:   ae.Name,
+			Reason: reason,
+		})
+	}
+
+	// Plan the changes against the set as it would be after the previous ones
+	sim := newEmoteSetSim(target)
+	items := []mutations.EmoteSetMutationSetEmoteItem{}
+	apply := func(op *model.EmoteSetOpInput, emote *model.Emote) error {
+		name, err := sim.apply(ctx, op, emote)
+		if err == nil {
+			items = append(items, mutations.EmoteSetMutationSetEmoteItem{
+				Action: mutations.ListItemAction(op.Action),
+				ID:     op.ID,
+				Name:   name,
+			})
+		}
+		return err
+	}
+
+	if strategy == model.EmoteSetImportStrategyReplace {
+		inSource := map[primitive.ObjectID]bool{}
+		for _, ae := range source.Emotes {
+			inSource[ae.ID] = true
+		}
+		for _, ae := range target.Emotes {
+			if !inSource[ae.ID] {
+				_ = apply(&model.EmoteSetOpInput{ID: ae.ID, Action: model.ListItemActionRemove}, nil)
+			}
+		}
+	}
+
+	for _, ae := range source.Emotes {
+		if ae.Emote == nil || ae.Emote.ID != ae.ID {
+			skip(ae, model.EmoteSetImportSkipReasonUnavailable)
+			continue
+		}
+		if !auth.CanSeeEmote(ctx, ae.Emote) {
+			skip(ae, model.EmoteSetImportSkipReasonPrivate)
+			continue
+		}
+
+		// The emote is already in the set: only its name may change when replacing
+		name := ae.Name
+		existing, isActive := sim.active[ae.ID]
+		if isActive && (strategy != model.EmoteSetImportStrategyReplace || existing == name) {
+			continue
+		}
+
+		if id, ok := sim.names[name]; ok && id != ae.ID {
+			conflict := &model.EmoteSetImportConflict{
+				Name:            name,
+				EmoteID:         ae.ID,
+				ExistingEmoteID: id,
+			}
+			result.Conflicts = append(result.Conflicts, conflict)
+
+			// When merging the imported emote takes the place of the existing one, unless it was imported too
+			if strategy != model.EmoteSetImportStrategyMerge || sim.changed[id] {
+				skip(ae, model.EmoteSetImportSkipReasonConflict)
+				continue
+			}
+			if err := apply(&model.EmoteSetOpInput{ID: id, Action: model.ListItemActionRemove}, nil); err != nil {
+				skip(ae, model.EmoteSetImportSkipReasonConflict)
+				continue
+			}
+			conflict.Replaced = true
+		}
+
+		if isActive {
+			_ = apply(&model.EmoteSetOpInput{ID: ae.ID, Action: model.ListItemActionUpdate, Name: &name}, ae.Emote)
+			continue
+		}
+		if sim.slots > 0 && len(sim.active) >= sim.slots {
+			skip(ae, model.EmoteSetImportSkipReasonNoSlots)
+			continue
+		}
+		if err := apply(&model.EmoteSetOpInput{ID: ae.ID, Action: model.ListItemActionAdd, Name: &name}, ae.Emote); err != nil {
+			skip(ae, model.EmoteSetImportSkipReasonUnavailable)
+			continue
+		}
+		result.Added++
+	}
+
+	// Apply all changes in a single write, unless the set was modified since it was planned against
+	if len(items) > 0 {
+		written, err := r.writeEmoteChanges(ctx, actor, target, sim, items)
+		if err != nil {
+			return nil, err
+		}
+		if !written {
+			return nil, errors.ErrInvalidRequest().SetDetail("The emote set is being modified, try again")
+		}
+		loaders.For(ctx).EmoteSetByID.Clear(target.ID)
+	}
+
+	result.EmoteSet, err = loaders.For(ctx).EmoteSetByID.Load(target.ID)
+	return result, err
+}
@@ -0,0 +1,230 @@
+package emoteset
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	EMOTE_SET_NAME_MAX = 40
+	EMOTE_SET_TAGS_MAX = 6
+)
+
+// Update: rename or retag an emote set
+func (r *ResolverOps) Update(ctx context.Context, obj *model.EmoteSetOps, name *string, tags []string) (*model.EmoteSet, error) {
+	actor := auth.For(ctx)
+
+	set, err := r.fetchManagedEmoteSet(ctx, actor, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	b := structures.NewEmoteSetBuilder(set)
+	if name != nil {
+		n := strings.TrimSpace(*name)
+		if n == "" || utf8.RuneCountInString(n) > EMOTE_SET_NAME_MAX {
+			return nil, errors.ErrInvalidRequest().SetDetail("Name must be between 1 and %d characters", EMOTE_SET_NAME_MAX)
+		}
+		b.SetName(n)
+	}
+	if tags != nil {
+		if len(tags) > EMOTE_SET_TAGS_MAX {
+			return nil, errors.ErrInvalidRequest().SetDetail("Too many tags (max %d)", EMOTE_SET_TAGS_MAX)
+		}
+		for _, t := range tags {
+			if !helpers.TagRegex.MatchString(t) {
+				return nil, errors.ErrInvalidRequest().SetDetail("Bad tag: %s", t)
+			}
+		}
+		b.SetTags(tags)
+	}
+
+	return r.editEmoteSet(ctx, actor, b)
+}
+
+// SetSlots: resize an emote set
+func (r *ResolverOps) SetSlots(ctx context.Context, obj *model.EmoteSetOps, slots int) (*model.EmoteSet, error) {
+	actor := auth.For(ctx)
+
+	set, err := r.fetchManagedEmoteSet(ctx, actor, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if slots < 1 {
+		return nil, errors.ErrInvalidRequest().SetDetail("slots cannot be less than 1")
+	}
+	if slots < len(set.Emotes) {
+		return nil, errors.ErrInvalidRequest().SetDetail("The set has %d emotes, remove some before lowering its slots", len(set.Emotes))
+	}
+
+	// Limit by the roles of the owner, unless the actor is a moderator
+	if !actor.HasPermission(structures.RolePermissionEditAnyEmoteSet) {
+		roleIDs := []primitive.ObjectID{}
+		if owner, err := loaders.For(ctx).UserByID.Load(set.OwnerID); err == nil && owner != nil {
+			for _, rol := range owner.Roles {
+				roleIDs = append(roleIDs, rol.ID)
+			}
+		}
+		if max := helpers.EmoteSetMaxSlots(r.Ctx, roleIDs); slots > max {
+			return nil, errors.ErrInsufficientPrivilege().SetDetail("The set cannot have more than %d slots", max)
+		}
+	}
+
+	// Limit by the connections using the set
+	users := []*structures.User{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+		"connections.emote_set_id": set.ID,
+	}, options.Find().SetProjection(bson.M{"connections": 1}))
+	if err == nil {
+		err = cur.All(ctx, &users)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch users of emote set")
+		return nil, errors.ErrInternalServerError()
+	}
+	for _, u := range users {
+		for _, con := range u.Connections {
+			if con.EmoteSetID == set.ID && con.EmoteSlots > 0 && slots > int(con.EmoteSlots) {
+				return nil, errors.ErrInvalidRequest().SetDetail("The set is used by a %s connection limited to %d slots", con.Platform, con.EmoteSlots)
+			}
+		}
+	}
+
+	return r.editEmoteSet(ctx, actor, structures.NewEmoteSetBuilder(set).SetEmoteSlots(int32(slots)))
+}
+
+// SetPrivileged: mark an emote set as privileged or not
+func (r *ResolverOps) SetPrivileged(ctx context.Context, obj *model.EmoteSetOps, privileged bool) (*model.EmoteSet, error) {
+	actor := auth.For(ctx)
+
+	set, err := r.fetchManagedEmoteSet(ctx, actor, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.editEmoteSet(ctx, actor, structures.NewEmoteSetBuilder(set).SetPrivileged(privileged))
+}
+
+// Delete: delete an emote set, unbinding it from the connections using it
+func (r *ResolverOps) Delete(ctx context.Context, obj *model.EmoteSetOps) (bool, error) {
+	actor := auth.For(ctx)
+	logF := logrus.WithField("emote_set_id", obj.ID)
+
+	set, err := r.fetchManagedEmoteSet(ctx, actor, obj.ID)
+	if err != nil {
+		return false, err
+	}
+	if sys := r.Ctx.Inst().Mongo.System(ctx); sys.EmoteSetID == set.ID {
+		return false, errors.ErrInvalidRequest().SetDetail("The global emote set cannot be deleted")
+	}
+
+	// Find the users using the set so that they are notified
+	users := []*structures.User{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+		"connections.emote_set_id": set.ID,
+	}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err == nil {
+		err = cur.All(ctx, &users)
+	}
+	if err != nil {
+		logF.WithError(err).Error("mongo, failed to fetch users of emote set")
+		return false, errors.ErrInternalServerError()
+	}
+	userIDs := make([]primitive.ObjectID, len(users))
+	for i, u := range users {
+		userIDs[i] = u.ID
+	}
+
+	// Unbind the set, including from connections bound since the users were found, then delete it
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).UpdateMany(ctx, bson.M{
+		"connections.emote_set_id": set.ID,
+	}, bson.M{
+		"$unset": bson.M{"connections.$[con].emote_set_id": 1},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"con.emote_set_id": set.ID}},
+	})); err != nil {
+		logF.WithError(err).Error("mongo, failed to unbind emote set from connections")
+		return false, errors.ErrInternalServerError()
+	}
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).DeleteOne(ctx, bson.M{"_id": set.ID}); err != nil {
+		logF.WithError(err).Error("mongo, failed to delete emote set")
+		return false, errors.ErrInternalServerError()
+	}
+
+	if _, err = r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteSetHistory).DeleteMany(ctx, bson.M{"emote_set_id": set.ID}); err != nil {
+		logF.WithError(err).Error("mongo, failed to delete emote set history")
+	}
+
+	// The emotes of the set are no longer used by it
+	items := make([]mutations.EmoteSetMutationSetEmoteItem, len(set.Emotes))
+	for i, ae := range set.Emotes {
+		items[i] = mutations.EmoteSetMutationSetEmoteItem{
+			Action: mutations.ListItemActionRemove,
+			ID:     ae.ID,
+			Name:   ae.Name,
+		}
+	}
+	helpers.RecordEmoteSetChanges(ctx, r.Ctx, set.ID, items)
+	r.publishEmoteSet(ctx, actor, set, userIDs)
+
+	loaders.For(ctx).EmoteSetByID.Clear(set.ID)
+	return true, nil
+}
+
+// fetchManagedEmoteSet retrieves an emote set which the actor is allowed to manage.
+// Ownership is checked by @isSelfOrEditor, but immutable and privileged sets can only be managed by moderators
+func (r *ResolverOps) fetchManagedEmoteSet(ctx context.Context, actor *structures.User, id primitive.ObjectID) (*structures.EmoteSet, error) {
+	set := &structures.EmoteSet{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(ctx, bson.M{"_id": id}).Decode(set); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmoteSet()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote set")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	if actor.HasPermission(structures.RolePermissionEditAnyEmoteSet) {
+		return set, nil
+	}
+	if set.Immutable || set.Privileged {
+		return nil, errors.ErrInsufficientPrivilege().SetDetail("This emote set cannot be modified")
+	}
+	if !actor.HasPermission(structures.RolePermissionEditEmoteSet) {
+		return nil, errors.ErrInsufficientPrivilege().SetDetail("You are not allowed to edit emote sets")
+	}
+
+	return set, nil
+}
+
+// editEmoteSet writes the changes of a builder and publishes them
+func (r *ResolverOps) editEmoteSet(ctx context.Context, actor *structures.User, b *structures.EmoteSetBuilder) (*model.EmoteSet, error) {
+	m := mutations.EmoteSetMutation{EmoteSetBuilder: b}
+	if _, err := m.Edit(ctx, r.Ctx.Inst().Mongo, mutations.EmoteSetMutationOptions{
+		Actor: actor,
+	}); err != nil {
+		logrus.WithError(err).WithField("emote_set_id", b.EmoteSet.ID).Error("failed to edit emote set")
+		return nil, err
+	}
+
+	r.recordSetEdit(ctx, actor, b.EmoteSet)
+	r.publishEmoteSet(ctx, actor, b.EmoteSet, nil)
+
+	loaders.For(ctx).EmoteSetByID.Clear(b.EmoteSet.ID)
+	return loaders.For(ctx).EmoteSetByID.Load(b.EmoteSet.ID)
+}
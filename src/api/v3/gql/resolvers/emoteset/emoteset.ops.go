@@ -2,7 +2,7 @@ package emoteset
 
 import (
 	"context"
-	"fmt"
+	"time"
 
 	"github.com/SevenTV/Common/errors"
 	"github.com/SevenTV/Common/mongo"
@@ -19,6 +19,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ResolverOps struct {
@@ -31,6 +32,9 @@ func NewOps(r types.Resolver) generated.EmoteSetOpsResolver {
 
 func (r *ResolverOps) Emotes(ctx context.Context, obj *model.EmoteSetOps, id primitive.ObjectID, action model.ListItemAction, nameArg *string) ([]*model.ActiveEmote, error) {
 	actor := auth.For(ctx)
+	if actor == nil {
+		return nil, errors.ErrUnauthorized()
+	}
 	logF := logrus.WithFields(logrus.Fields{
 		"emote_set_id": obj.ID,
 		"emote_id":     id,
@@ -50,68 +54,131 @@ func (r *ResolverOps) Emotes(ctx context.Context, obj *model.EmoteSetOps, id pri
 	}
 
 	// Mutate the thing
-	m := mutations.EmoteSetMutation{EmoteSetBuilder: b}
+	items := []mutations.EmoteSetMutationSetEmoteItem{{
+		Action: mutations.ListItemAction(action),
+		ID:     id,
+		Name:   name,
+		Flags:  0,
+	}}
+	if err := r.applyEmoteChanges(ctx, actor, b.EmoteSet, items); err != nil {
+		return nil, err
+	}
+
+	return r.activeEmotes(ctx, b.EmoteSet)
+}
+
+// applyEmoteChanges writes changes to the emotes of a set in a single mutation, then records and publishes them
+func (r *ResolverOps) applyEmoteChanges(ctx context.Context, actor *structures.User, set *structures.EmoteSet, items []mutations.EmoteSetMutationSetEmoteItem) error {
+	before := activeEmoteNames(set)
+
+	m := mutations.EmoteSetMutation{EmoteSetBuilder: structures.NewEmoteSetBuilder(set)}
 	if _, err := m.SetEmote(ctx, r.Ctx.Inst().Mongo, mutations.EmoteSetMutationSetEmoteOptions{
-		Actor: actor,
-		Emotes: []mutations.EmoteSetMutationSetEmoteItem{{
-			Action: mutations.ListItemAction(action),
-			ID:     id,
-			Name:   name,
-			Flags:  0,
-		}},
+		Actor:  actor,
+		Emotes: items,
 	}); err != nil {
-		logF.WithError(err).Error("failed to update emotes in set")
-		return nil, err
+		logrus.WithError(err).WithField("emote_set_id", set.ID).Error("failed to update emotes in set")
+		return err
 	}
 
-	// Clear cache keys for active sets / channel count
-	k := r.Ctx.Inst().Redis.ComposeKey("gql-v3", fmt.Sprintf("emote:%s", id.Hex()))
-	_, _ = r.Ctx.Inst().Redis.Del(ctx, k+":active_sets")
-	_, _ = r.Ctx.Inst().Redis.Del(ctx, k+":channel_count")
+	r.emoteSetChanged(ctx, actor, set, before, items)
+	return nil
+}
 
-	emoteIDs := make([]primitive.ObjectID, len(b.EmoteSet.Emotes))
-	for i, e := range b.EmoteSet.Emotes {
-		emoteIDs[i] = e.ID
+// writeEmoteChanges writes the emotes of a set as planned by a simulation, provided that the set was not modified since it was fetched,
+// then records and publishes the changes. It returns false without writing anything if the set was modified.
+// Ownership is checked by @isSelfOrEditor on the operations using it
+func (r *ResolverOps) writeEmoteChanges(ctx context.Context, actor *structures.User, set *structures.EmoteSet, sim *emoteSetSim, items []mutations.EmoteSetMutationSetEmoteItem) (bool, error) {
+	if !actor.HasPermission(structures.RolePermissionEditAnyEmoteSet) && (set.Immutable || set.Privileged) {
+		return false, errors.ErrInsufficientPrivilege().SetDetail("This emote set cannot be modified")
+	}
+	before := activeEmoteNames(set)
+
+	ids := make(bson.A, len(set.Emotes))
+	names := make(bson.A, len(set.Emotes))
+	for i, ae := range set.Emotes {
+		ids[i] = ae.ID
+		names[i] = ae.Name
+	}
+	res, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).UpdateOne(ctx, bson.M{
+		"_id":         set.ID,
+		"emote_slots": set.EmoteSlots,
+		"$expr": bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$emotes.id", bson.A{}}}, ids}},
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$emotes.name", bson.A{}}}, names}},
+		}},
+	}, bson.M{"$set": bson.M{"emotes": sim.emotes(set, time.Now())}})
+	if err != nil {
+		logrus.WithError(err).WithField("emote_set_id", set.ID).Error("mongo, failed to update emotes in set")
+		return false, errors.ErrInternalServerError()
+	}
+	if res.MatchedCount == 0 {
+		return false, nil
 	}
 
-	// Publish updates for;
-	// emote set, owner of emote set, actor
+	r.emoteSetChanged(ctx, actor, set, before, items)
+	return true, nil
+}
+
+// emoteSetChanged records changes made to the emotes of a set and publishes a single update.
+// before holds the names of the emotes which were in the set prior to the changes
+func (r *ResolverOps) emoteSetChanged(ctx context.Context, actor *structures.User, set *structures.EmoteSet, before map[primitive.ObjectID]string, items []mutations.EmoteSetMutationSetEmoteItem) {
+	helpers.RecordEmoteSetChanges(ctx, r.Ctx, set.ID, items)
+	r.recordHistory(ctx, actor, set, before, items)
+	r.publishEmoteSet(ctx, actor, set, nil)
+}
+
+// publishEmoteSet publishes an update for the set, its owner, the actor and the users who have the set active,
+// or those given if the set was unbound from their connections
+func (r *ResolverOps) publishEmoteSet(ctx context.Context, actor *structures.User, set *structures.EmoteSet, userIDs []primitive.ObjectID) {
 	go func() {
 		// Find users that have this set active
-		sentToActor := false
-		sentToOwner := false
-		cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
-			"connections.emote_set_id": b.EmoteSet.ID,
-		})
-		if err == nil {
-			for cur.Next(ctx) {
-				u := &structures.User{}
-				if err = cur.Decode(u); err != nil {
-					continue
+		if userIDs == nil {
+			cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Find(ctx, bson.M{
+				"connections.emote_set_id": set.ID,
+			}, options.Find().SetProjection(bson.M{"_id": 1}))
+			if err == nil {
+				for cur.Next(ctx) {
+					u := &structures.User{}
+					if err = cur.Decode(u); err != nil {
+						continue
+					}
+					userIDs = append(userIDs, u.ID)
 				}
+			}
+		}
 
-				events.Publish(r.Ctx, "users", u.ID)
-				if u.ID == actor.ID {
-					sentToActor = true
-				} else if u.ID == b.EmoteSet.OwnerID {
-					sentToOwner = true
-				}
+		sentToActor := false
+		sentToOwner := false
+		for _, id := range userIDs {
+			events.Publish(r.Ctx, "users", id)
+			if id == actor.ID {
+				sentToActor = true
+			} else if id == set.OwnerID {
+				sentToOwner = true
 			}
 		}
 
 		// Publish an emote set update
-		events.Publish(r.Ctx, "emote_sets", b.EmoteSet.ID)
+		events.Publish(r.Ctx, "emote_sets", set.ID)
 		// Send user update for set owner
-		if !sentToOwner && b.EmoteSet.OwnerID != actor.ID {
-			events.Publish(r.Ctx, "users", b.EmoteSet.OwnerID)
+		if !sentToOwner && set.OwnerID != actor.ID {
+			events.Publish(r.Ctx, "users", set.OwnerID)
 		}
 		// Send user update for actor
 		if !sentToActor {
 			events.Publish(r.Ctx, "users", actor.ID)
 		}
 	}()
+}
+
+// activeEmotes returns the emotes of a set along with the emotes they refer to
+func (r *ResolverOps) activeEmotes(ctx context.Context, set *structures.EmoteSet) ([]*model.ActiveEmote, error) {
+	emoteIDs := make([]primitive.ObjectID, len(set.Emotes))
+	for i, e := range set.Emotes {
+		emoteIDs[i] = e.ID
+	}
 
-	setModel := helpers.EmoteSetStructureToModel(r.Ctx, b.EmoteSet)
+	setModel := helpers.EmoteSetStructureToModel(r.Ctx, set)
 	emotes, errs := loaders.For(ctx).EmoteByID.LoadAll(emoteIDs)
 	for i, e := range emotes {
 		if ae := setModel.Emotes[i]; ae != nil {
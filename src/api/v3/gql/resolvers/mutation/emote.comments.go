@@ -0,0 +1,191 @@
+package mutation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/events"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	EMOTE_COMMENT_MAX_LENGTH      = 500
+	EMOTE_COMMENTS_PER_MINUTE     = 5
+	EMOTE_COMMENT_RATE_LIMIT_SPAN = time.Minute
+)
+
+// Comments are messages of the EMOTE_COMMENT kind, attached to the emote's ID rather than that of one of its versions.
+// Subscribers of an emote's comments receive the ID of each comment which was posted, edited or deleted
+
+// PostEmoteComment: add a comment to the thread of an emote
+func (r *Resolver) PostEmoteComment(ctx context.Context, emoteID primitive.ObjectID, content string) (*model.EmoteComment, error) {
+	actor := auth.For(ctx)
+
+	content, err := validateEmoteComment(content)
+	if err != nil {
+		return nil, err
+	}
+
+	emote := &structures.Emote{}
+	if err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"$or": bson.A{bson.M{"_id": emoteID}, bson.M{"versions.id": emoteID}},
+	}, options.FindOne().SetProjection(bson.M{"flags": 1, "owner_id": 1, "versions.state": 1})).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmote()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote")
+		return nil, errors.ErrInternalServerError()
+	}
+	if len(emote.Versions) > 0 && emote.Versions[0].State.Lifecycle == structures.EmoteLifecycleDeleted {
+		return nil, errors.ErrUnknownEmote()
+	}
+	if emote.Flags&structures.EmoteFlagsPrivate != 0 && !auth.CanSeePrivateEmotesOf(ctx, emote.OwnerID) {
+		return nil, errors.ErrUnknownEmote()
+	}
+
+	if err = r.checkCommentRateLimit(ctx, actor); err != nil {
+		return nil, err
+	}
+
+	mb := structures.NewMessageBuilder(&structures.Message{}).
+		SetKind(structures.MessageKindEmoteComment).
+		SetAuthorID(actor.ID).
+		SetTimestamp(time.Now()).
+		AsEmoteComment(structures.MessageDataEmoteComment{
+			EmoteID: emote.ID,
+			Content: content,
+		})
+	res, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameMessages).InsertOne(ctx, mb.Message)
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to create emote comment")
+		return nil, errors.ErrInternalServerError()
+	}
+	mb.Message.ID, _ = res.InsertedID.(primitive.ObjectID)
+
+	events.PublishMessage(r.Ctx, "emote_comments", emote.ID, mb.Message.ID.Hex())
+
+	return helpers.EmoteCommentStructureToModel(r.Ctx, mb.Message), nil
+}
+
+// EditEmoteComment: change the content of a comment
+func (r *Resolver) EditEmoteComment(ctx context.Context, id primitive.ObjectID, content string) (*model.EmoteComment, error) {
+	actor := auth.For(ctx)
+
+	content, err := validateEmoteComment(content)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := r.fetchEmoteComment(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mb := structures.NewMessageBuilder(msg)
+	d := mb.DecodeEmoteComment()
+	d.Content = content
+	mb.AsEmoteComment(*d)
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameMessages).UpdateOne(ctx, bson.M{"_id": msg.ID}, bson.M{
+		"$set": bson.M{"data": msg.Data},
+	}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to edit emote comment")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	events.PublishMessage(r.Ctx, "emote_comments", d.EmoteID, msg.ID.Hex())
+
+	return helpers.EmoteCommentStructureToModel(r.Ctx, msg), nil
+}
+
+// DeleteEmoteComment: remove a comment
+func (r *Resolver) DeleteEmoteComment(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	actor := auth.For(ctx)
+
+	msg, err := r.fetchEmoteComment(ctx, actor, id)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameMessages).DeleteOne(ctx, bson.M{"_id": msg.ID}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to delete emote comment")
+		return false, errors.ErrInternalServerError()
+	}
+
+	d := structures.NewMessageBuilder(msg).DecodeEmoteComment()
+	events.PublishMessage(r.Ctx, "emote_comments", d.EmoteID, msg.ID.Hex())
+
+	return true, nil
+}
+
+// fetchEmoteComment retrieves a comment which the actor is allowed to modify: their own, or any if they are a moderator
+func (r *Resolver) fetchEmoteComment(ctx context.Context, actor *structures.User, id primitive.ObjectID) (*structures.Message, error) {
+	msg := &structures.Message{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameMessages).FindOne(ctx, bson.M{
+		"_id":  id,
+		"kind": structures.MessageKindEmoteComment,
+	}).Decode(msg); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownMessage()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote comment")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	if msg.AuthorID != actor.ID && !actor.HasPermission(structures.RolePermissionEditAnyEmote) {
+		return nil, errors.ErrInsufficientPrivilege().SetDetail("You can only modify your own comments")
+	}
+	return msg, nil
+}
+
+// checkCommentRateLimit counts a comment towards the actor's limit, failing once it is reached. Moderators are exempt
+func (r *Resolver) checkCommentRateLimit(ctx context.Context, actor *structures.User) error {
+	if actor.HasPermission(structures.RolePermissionEditAnyEmote) {
+		return nil
+	}
+
+	limit := r.Ctx.Config().Emotes.CommentsPerMinute
+	if limit <= 0 {
+		limit = EMOTE_COMMENTS_PER_MINUTE
+	}
+
+	k := r.Ctx.Inst().Redis.ComposeKey("gql-v3", fmt.Sprintf("emote-comments:rate:%s", actor.ID.Hex())).String()
+	// The counter is created along with its expiry in the same transaction, so that it cannot outlive the span
+	var count *redis.IntCmd
+	if _, err := r.Ctx.Inst().Redis.RawClient().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetNX(ctx, k, 0, EMOTE_COMMENT_RATE_LIMIT_SPAN)
+		count = pipe.Incr(ctx, k)
+		return nil
+	}); err != nil {
+		logrus.WithError(err).Error("redis, failed to count emote comments")
+		return errors.ErrInternalServerError()
+	}
+	if count.Val() > int64(limit) {
+		return errors.ErrRateLimited().SetDetail("You can post up to %d comments per minute", limit)
+	}
+
+	return nil
+}
+
+func validateEmoteComment(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", errors.ErrInvalidRequest().SetDetail("Comment cannot be empty")
+	}
+	if len([]rune(content)) > EMOTE_COMMENT_MAX_LENGTH {
+		return "", errors.ErrInvalidRequest().SetDetail("Comment too long (max %d characters)", EMOTE_COMMENT_MAX_LENGTH)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,245 @@
+package mutation
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/events"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const EMOTE_DELETION_RETENTION_DAYS = 30
+
+// DeleteEmote: soft-delete an emote, removing it from all emote sets
+func (r *Resolver) DeleteEmote(ctx context.Context, id primitive.ObjectID, reason *string) (bool, error) {
+	actor := auth.For(ctx)
+
+	emote, err := r.fetchEmote(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if err = r.checkEmotePermission(ctx, actor, emote); err != nil {
+		return false, err
+	}
+
+	// A deletion is moderated when the actor acts on privilege rather than ownership
+	moderated := emote.OwnerID != actor.ID
+	for _, ed := range actor.EditorOf {
+		if ed.ID == emote.OwnerID && ed.HasPermission(structures.UserEditorPermissionManageOwnedEmotes) {
+			moderated = false
+			break
+		}
+	}
+
+	lifecycles := make(map[string]structures.EmoteLifecycle, len(emote.Versions))
+	versionIDs := make([]primitive.ObjectID, len(emote.Versions))
+	for i, ver := range emote.Versions {
+		if ver.State.Lifecycle == structures.EmoteLifecycleDeleted {
+			return false, errors.ErrInvalidRequest().SetDetail("This emote is already deleted")
+		}
+		lifecycles[ver.ID.Hex()] = ver.State.Lifecycle
+		versionIDs[i] = ver.ID
+	}
+
+	// Find the emote sets referencing any version of the emote
+	setIDs := []primitive.ObjectID{}
+	active := []helpers.EmoteDeletionSet{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).Find(ctx, bson.M{
+		"emotes.id": bson.M{"$in": versionIDs},
+	}, options.Find().SetProjection(bson.M{"_id": 1, "emotes": 1}))
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote sets")
+		return false, errors.ErrInternalServerError()
+	}
+	sets := []*structures.EmoteSet{}
+	if err = cur.All(ctx, &sets); err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote sets")
+		return false, errors.ErrInternalServerError()
+	}
+	isVersion := make(map[primitive.ObjectID]bool, len(versionIDs))
+	for _, id := range versionIDs {
+		isVersion[id] = true
+	}
+	for _, set := range sets {
+		setIDs = append(setIDs, set.ID)
+		for _, ae := range set.Emotes {
+			if isVersion[ae.ID] {
+				active = append(active, helpers.EmoteDeletionSet{
+					EmoteSetID: set.ID,
+					ID:         ae.ID,
+					Name:       ae.Name,
+					Flags:      ae.Flags,
+				})
+			}
+		}
+	}
+
+	// Keep a record allowing the emote to be restored
+	retention := r.Ctx.Config().Emotes.DeletionRetentionDays
+	if retention <= 0 {
+		retention = EMOTE_DELETION_RETENTION_DAYS
+	}
+	record := &helpers.EmoteDeletion{
+		ID:         emote.ID,
+		ActorID:    actor.ID,
+		Moderated:  moderated,
+		Lifecycles: lifecycles,
+		EmoteSets:  active,
+		DeletedAt:  time.Now(),
+		ExpiresAt:  time.Now().AddDate(0, 0, retention),
+	}
+	if reason != nil {
+		record.Reason = *reason
+	}
+	if _, err = r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteDeletions).ReplaceOne(ctx, bson.M{"_id": record.ID}, record, options.Replace().SetUpsert(true)); err != nil {
+		logrus.WithError(err).Error("mongo, failed to create emote deletion record")
+		return false, errors.ErrInternalServerError()
+	}
+
+	// Mark all versions as deleted
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(ctx, bson.M{"_id": emote.ID}, bson.M{
+		"$set": bson.M{"versions.$[].state.lifecycle": structures.EmoteLifecycleDeleted},
+	}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to delete emote")
+		return false, errors.ErrInternalServerError()
+	}
+
+	// Remove from emote sets
+	if len(setIDs) > 0 {
+		if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).UpdateMany(ctx, bson.M{
+			"_id": bson.M{"$in": setIDs},
+		}, bson.M{
+			"$pull": bson.M{"emotes": bson.M{"id": bson.M{"$in": versionIDs}}},
+		}); err != nil {
+			logrus.WithError(err).Error("mongo, failed to remove deleted emote from emote sets")
+			return false, errors.ErrInternalServerError()
+		}
+	}
+
+	// Count the removals as any other, by set
+	removed := map[primitive.ObjectID][]mutations.EmoteSetMutationSetEmoteItem{}
+	for _, as := range active {
+		removed[as.EmoteSetID] = append(removed[as.EmoteSetID], mutations.EmoteSetMutationSetEmoteItem{
+			Action: mutations.ListItemActionRemove,
+			ID:     as.ID,
+			Name:   as.Name,
+		})
+	}
+	for _, setID := range setIDs {
+		helpers.RecordEmoteSetChanges(ctx, r.Ctx, setID, removed[setID])
+		events.Publish(r.Ctx, "emote_sets", setID)
+		loaders.For(ctx).EmoteSetByID.Clear(setID)
+	}
+	events.Publish(r.Ctx, "emotes", emote.ID)
+
+	loaders.For(ctx).EmoteByID.Clear(emote.ID)
+	return true, nil
+}
+
+// RestoreEmote: undo the deletion of an emote, provided its record has not lapsed
+func (r *Resolver) RestoreEmote(ctx context.Context, id primitive.ObjectID) (*model.Emote, error) {
+	actor := auth.For(ctx)
+
+	record := &helpers.EmoteDeletion{}
+	if err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteDeletions).FindOne(ctx, bson.M{
+		"_id":        id,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(record); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmote().SetDetail("No restorable deletion")
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote deletion record")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	emote, err := r.fetchEmote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deletions made by moderators can only be undone by moderators
+	if record.Moderated && !actor.HasPermission(structures.RolePermissionEditAnyEmote) {
+		return nil, errors.ErrInsufficientPrivilege().SetDetail("This emote was deleted by a moderator")
+	}
+	if err = r.checkEmotePermission(ctx, actor, emote); err != nil {
+		return nil, err
+	}
+
+	// Restore the lifecycle of each version
+	models := []mongo.WriteModel{}
+	for _, ver := range emote.Versions {
+		lc, ok := record.Lifecycles[ver.ID.Hex()]
+		if !ok {
+			lc = structures.EmoteLifecycleDisabled
+		}
+
+		models = append(models, &mongo.UpdateOneModel{
+			Filter: bson.M{"_id": emote.ID, "versions.id": ver.ID},
+			Update: bson.M{"$set": bson.M{"versions.$.state.lifecycle": lc}},
+		})
+	}
+	if len(models) > 0 {
+		if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).BulkWrite(ctx, models); err != nil {
+			logrus.WithError(err).Error("mongo, failed to restore emote")
+			return nil, errors.ErrInternalServerError()
+		}
+	}
+
+	// Add the emote back to the sets it was removed from, unless they have no slots left or another emote took its name
+	restoredSets := map[primitive.ObjectID][]mutations.EmoteSetMutationSetEmoteItem{}
+	for _, as := range record.EmoteSets {
+		res, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).UpdateOne(ctx, bson.M{
+			"_id":         as.EmoteSetID,
+			"emotes.id":   bson.M{"$ne": as.ID},
+			"emotes.name": bson.M{"$ne": as.Name},
+			"$expr": bson.M{"$or": bson.A{
+				bson.M{"$lte": bson.A{"$emote_slots", 0}},
+				bson.M{"$lt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$emotes", bson.A{}}}}, "$emote_slots"}},
+			}},
+		}, bson.M{
+			"$push": bson.M{"emotes": &structures.ActiveEmote{
+				ID:        as.ID,
+				Name:      as.Name,
+				Flags:     as.Flags,
+				Timestamp: time.Now(),
+			}},
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("emote_set_id", as.EmoteSetID).Error("mongo, failed to restore emote to emote set")
+			continue
+		}
+		if res.ModifiedCount > 0 {
+			restoredSets[as.EmoteSetID] = append(restoredSets[as.EmoteSetID], mutations.EmoteSetMutationSetEmoteItem{
+				Action: mutations.ListItemActionAdd,
+				ID:     as.ID,
+				Name:   as.Name,
+			})
+		}
+	}
+
+	if _, err = r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteDeletions).DeleteOne(ctx, bson.M{"_id": record.ID}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to remove emote deletion record")
+	}
+
+	for setID, items := range restoredSets {
+		helpers.RecordEmoteSetChanges(ctx, r.Ctx, setID, items)
+		events.Publish(r.Ctx, "emote_sets", setID)
+		loaders.For(ctx).EmoteSetByID.Clear(setID)
+	}
+	events.Publish(r.Ctx, "emotes", emote.ID)
+
+	loaders.For(ctx).EmoteByID.Clear(emote.ID)
+	return loaders.For(ctx).EmoteByID.Load(emote.ID)
+}
@@ -0,0 +1,323 @@
+package mutation
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/events"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/SevenTV/GQL/src/emotes"
+	"github.com/SevenTV/GQL/src/images"
+	"github.com/SevenTV/GQL/src/instance"
+	"github.com/SevenTV/GQL/src/storage"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EMOTE_UPLOAD_MAX_SIZE   = 7000000
+	EMOTE_UPLOAD_MAX_WIDTH  = 1000
+	EMOTE_UPLOAD_MAX_HEIGHT = 1000
+)
+
+const EMOTE_TAGS_MAX = 6
+
+// CreateEmote: upload a new emote
+func (r *Resolver) CreateEmote(ctx context.Context, data model.CreateEmoteInput, file graphql.Upload) (*model.Emote, error) {
+	actor := auth.For(ctx)
+
+	if !helpers.EmoteNameRegex.MatchString(data.Name) {
+		return nil, errors.ErrEmoteNameInvalid()
+	}
+
+	// Validate flags
+	flags := structures.EmoteFlag(0)
+	if data.Flags != nil {
+		flags = structures.EmoteFlag(*data.Flags)
+		if flags&structures.EmoteFlagsZeroWidth != 0 && !actor.HasPermission(structures.RolePermissionFeatureZeroWidthEmoteType) {
+			return nil, errors.ErrInsufficientPrivilege().SetDetail("You cannot create zero-width emotes")
+		}
+	}
+
+	// Validate tags
+	tags := []string{}
+	if data.Tags != nil {
+		if err := r.validateEmoteTags(ctx, data.Tags); err != nil {
+			return nil, err
+		}
+		tags = data.Tags
+	}
+
+	// Read and validate the file
+	b, meta, err := r.readEmoteUpload(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the original file
+	id := primitive.NewObjectID()
+	if err = r.Ctx.Inst().Storage.Put(ctx, storage.EmoteOriginalKey(id), b, string(meta.Format)); err != nil {
+		logrus.WithError(err).Error("storage, failed to store emote upload")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	// Set up emote builder
+	description := ""
+	if data.Description != nil {
+		description = *data.Description
+	}
+	eb := structures.NewEmoteBuilder(&structures.Emote{ID: id}).
+		SetName(data.Name).
+		SetOwnerID(actor.ID).
+		SetFlags(flags).
+		SetTags(tags, true).
+		AddVersion(&structures.EmoteVersion{
+			ID:          id,
+			Name:        data.Name,
+			Description: description,
+			Timestamp:   time.Now(),
+			FrameCount:  int32(meta.FrameCount),
+			State: structures.EmoteVersionState{
+				Lifecycle: structures.EmoteLifecyclePending,
+			},
+		})
+	m := mutations.EmoteMutation{
+		EmoteBuilder: eb,
+	}
+
+	// Execute mutation
+	if _, err = m.Create(ctx, r.Ctx.Inst().Mongo, mutations.EmoteMutationOptions{
+		Actor: actor,
+	}); err != nil {
+		_ = r.Ctx.Inst().Storage.Delete(ctx, storage.EmoteOriginalKey(id))
+		return nil, err
+	}
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(ctx, bson.M{
+		"_id":         id,
+		"versions.id": id,
+	}, bson.M{"$set": bson.M{
+		"default_version_id": id,
+		"versions.$.review":  emotes.ReviewStatePending,
+	}}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to set emote version states")
+	}
+
+	// Hand off to processing; the emote remains pending until it is picked up
+	if err = r.Ctx.Inst().Jobs.EnqueueEmote(ctx, instance.EmoteJob{
+		EmoteID:   id,
+		VersionID: id,
+	}); err != nil {
+		logrus.WithError(err).WithField("emote_id", id.Hex()).Error("jobs, failed to enqueue emote")
+	}
+
+	return loaders.For(ctx).EmoteByID.Load(id)
+}
+
+// EditEmote: modify an emote's name, flags, tags or owner
+func (r *Resolver) EditEmote(ctx context.Context, emoteID primitive.ObjectID, data model.EditEmoteInput) (*model.Emote, error) {
+	actor := auth.For(ctx)
+
+	emote, err := r.fetchEmote(ctx, emoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check permissions
+	if err = r.checkEmotePermission(ctx, actor, emote); err != nil {
+		return nil, err
+	}
+
+	b := structures.NewEmoteBuilder(emote)
+
+	// Name & description
+	// These are written to a specific version if one was requested
+	if data.Name != nil && !helpers.EmoteNameRegex.MatchString(*data.Name) {
+		return nil, errors.ErrEmoteNameInvalid()
+	}
+	if data.VersionID != nil {
+		ver, _ := b.GetVersion(*data.VersionID)
+		if ver == nil {
+			return nil, errors.ErrUnknownEmote().SetDetail("Unknown Version")
+		}
+		if data.Name != nil {
+			ver.Name = *data.Name
+		}
+		if data.Description != nil {
+			ver.Description = *data.Description
+		}
+		b.UpdateVersion(ver.ID, ver)
+	} else if data.Name != nil {
+		b.SetName(*data.Name)
+	}
+
+	// Flags
+	if data.Flags != nil {
+		flags := structures.EmoteFlag(*data.Flags)
+		changed := flags ^ emote.Flags
+		if changed&structures.EmoteFlagsZeroWidth != 0 && !actor.HasPermission(structures.RolePermissionFeatureZeroWidthEmoteType) {
+			return nil, errors.ErrInsufficientPrivilege().SetDetail("You cannot change the zero-width flag")
+		}
+		b.SetFlags(flags)
+	}
+
+	// Tags
+	if data.Tags != nil {
+		if err = r.validateEmoteTags(ctx, data.Tags); err != nil {
+			return nil, err
+		}
+		b.SetTags(data.Tags, true)
+	}
+
+	// Owner
+	// Reassigning directly is reserved to privileged users, others must request a transfer
+	if data.OwnerID != nil {
+		ownerID, err := primitive.ObjectIDFromHex(*data.OwnerID)
+		if err != nil {
+			return nil, errors.ErrBadObjectID()
+		}
+		if !actor.HasPermission(structures.RolePermissionEditAnyEmote) {
+			return nil, errors.ErrInsufficientPrivilege().SetDetail("Use requestEmoteTransfer to transfer ownership of this emote")
+		}
+		if n, _ := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).CountDocuments(ctx, bson.M{"_id": ownerID}); n == 0 {
+			return nil, errors.ErrUnknownUser()
+		}
+		b.SetOwnerID(ownerID)
+	}
+
+	// Execute mutation
+	m := mutations.EmoteMutation{
+		EmoteBuilder: b,
+	}
+	if _, err = m.Edit(ctx, r.Ctx.Inst().Mongo, mutations.EmoteEditOptions{
+		Actor: actor,
+	}); err != nil {
+		if err == structures.ErrInsufficientPrivilege {
+			return nil, errors.ErrInsufficientPrivilege()
+		}
+		logrus.WithError(err).Error("mutation, failed to edit emote")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	events.Publish(r.Ctx, "emotes", emote.ID)
+
+	loaders.For(ctx).EmoteByID.Clear(emote.ID)
+	return loaders.For(ctx).EmoteByID.Load(emote.ID)
+}
+
+// readEmoteUpload reads an uploaded image, ensuring it is within the configured size limits
+func (r *Resolver) readEmoteUpload(file graphql.Upload) ([]byte, images.Metadata, error) {
+	cfg := r.Ctx.Config().Upload
+	maxSize := int64(EMOTE_UPLOAD_MAX_SIZE)
+	if cfg.MaxSize > 0 {
+		maxSize = cfg.MaxSize
+	}
+	maxWidth, maxHeight := EMOTE_UPLOAD_MAX_WIDTH, EMOTE_UPLOAD_MAX_HEIGHT
+	if cfg.MaxWidth > 0 {
+		maxWidth = cfg.MaxWidth
+	}
+	if cfg.MaxHeight > 0 {
+		maxHeight = cfg.MaxHeight
+	}
+
+	if file.Size > maxSize {
+		return nil, images.Metadata{}, errors.ErrInvalidRequest().SetDetail("File too large (max %d bytes)", maxSize)
+	}
+	b, err := io.ReadAll(io.LimitReader(file.File, maxSize+1))
+	if err != nil {
+		return nil, images.Metadata{}, errors.ErrInvalidRequest().SetDetail("Could not read file")
+	}
+	if int64(len(b)) > maxSize {
+		return nil, images.Metadata{}, errors.ErrInvalidRequest().SetDetail("File too large (max %d bytes)", maxSize)
+	}
+
+	meta, err := images.DecodeMetadata(b)
+	if err != nil {
+		return nil, meta, errors.ErrInvalidRequest().SetDetail("Bad image: %s", err.Error())
+	}
+	if meta.Width > maxWidth || meta.Height > maxHeight {
+		return nil, meta, errors.ErrInvalidRequest().SetDetail("Image too large (max %dx%d)", maxWidth, maxHeight)
+	}
+
+	return b, meta, nil
+}
+
+// fetchEmote retrieves an emote structure for modification
+func (r *Resolver) fetchEmote(ctx context.Context, id primitive.ObjectID) (*structures.Emote, error) {
+	emote := &structures.Emote{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{"_id": id}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmote()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	return emote, nil
+}
+
+// checkEmotePermission verifies that the actor is allowed to modify the emote:
+// they must own it, be a privileged editor of its owner or be allowed to edit any emote
+func (r *Resolver) checkEmotePermission(ctx context.Context, actor *structures.User, emote *structures.Emote) error {
+	editables, err := r.Ctx.Inst().Query.UserEditorOf(ctx, actor.ID)
+	if err != nil {
+		logrus.WithError(err).Error("query, failed to fetch editables")
+		return errors.ErrInternalServerError()
+	}
+	actor.EditorOf = editables
+
+	if actor.HasPermission(structures.RolePermissionEditAnyEmote) {
+		return nil
+	}
+	if !actor.HasPermission(structures.RolePermissionEditEmote) {
+		return errors.ErrInsufficientPrivilege().SetDetail("You are not allowed to edit emotes")
+	}
+
+	isEditor := false
+	for _, ed := range editables {
+		if ed.ID == emote.OwnerID && ed.HasPermission(structures.UserEditorPermissionManageOwnedEmotes) {
+			isEditor = true
+			break
+		}
+	}
+	if emote.OwnerID != actor.ID && !isEditor {
+		return errors.ErrInsufficientPrivilege().SetDetail("You do not have permission to edit this emote")
+	}
+
+	return nil
+}
+
+func (r *Resolver) validateEmoteTags(ctx context.Context, tags []string) error {
+	if len(tags) > EMOTE_TAGS_MAX {
+		return errors.ErrInvalidRequest().SetDetail("Too many tags (max %d)", EMOTE_TAGS_MAX)
+	}
+	for _, t := range tags {
+		if !helpers.TagRegex.MatchString(t) {
+			return errors.ErrInvalidRequest().SetDetail("Bad tag: %s", t)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	banned, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteTagBans).CountDocuments(ctx, bson.M{"_id": bson.M{"$in": tags}})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to count banned emote tags")
+		return errors.ErrInternalServerError()
+	}
+	if banned > 0 {
+		return errors.ErrInvalidRequest().SetDetail("One or more tags are not allowed")
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package mutation
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/events"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/emotes"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// New versions await review and are unlisted until approved by a moderator. Rejected versions are disabled.
+// Each version is decided once: the decision is recorded in its review state.
+
+// ApproveEmote: list a version of an emote awaiting review
+func (r *Resolver) ApproveEmote(ctx context.Context, id primitive.ObjectID) (*model.Emote, error) {
+	return r.decideEmote(ctx, id, true, "")
+}
+
+// RejectEmote: disable a version of an emote awaiting review
+func (r *Resolver) RejectEmote(ctx context.Context, id primitive.ObjectID, reason string) (*model.Emote, error) {
+	return r.decideEmote(ctx, id, false, reason)
+}
+
+func (r *Resolver) decideEmote(ctx context.Context, id primitive.ObjectID, approve bool, reason string) (*model.Emote, error) {
+	actor := auth.For(ctx)
+
+	// The ID is that of the version to decide, or of the emote when deciding the first of its versions awaiting review
+	emote := &structures.Emote{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{"versions.id": id}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmote()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote")
+		return nil, errors.ErrInternalServerError()
+	}
+	states, err := r.fetchEmoteVersionStates(ctx, emote)
+	if err != nil {
+		return nil, err
+	}
+
+	var ver *structures.EmoteVersion
+	for _, v := range emote.Versions {
+		if states.ReviewOf(v.ID) != emotes.ReviewStatePending {
+			continue
+		}
+		if v.ID == id {
+			ver = v
+			break
+		}
+		if ver == nil && id == emote.ID {
+			ver = v
+		}
+	}
+	if ver == nil || ver.State.Lifecycle != structures.EmoteLifecycleLive {
+		return nil, errors.ErrInvalidRequest().SetDetail("This emote is not awaiting review")
+	}
+
+	review := emotes.ReviewStateApproved
+	state := bson.M{"versions.$.state.listed": approve}
+	if !approve {
+		review = emotes.ReviewStateRejected
+		state["versions.$.state.lifecycle"] = structures.EmoteLifecycleDisabled
+	}
+	state["versions.$.review"] = review
+
+	// The version must still be awaiting review, so that concurrent decisions cannot both apply
+	res, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(ctx, bson.M{
+		"_id": emote.ID,
+		"versions": bson.M{"$elemMatch": bson.M{
+			"id":     ver.ID,
+			"review": emotes.ReviewStatePending,
+		}},
+	}, bson.M{"$set": state})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to update emote moderation state")
+		return nil, errors.ErrInternalServerError()
+	}
+	if res.MatchedCount == 0 {
+		return nil, errors.ErrInvalidRequest().SetDetail("This emote is not awaiting review")
+	}
+
+	emotes.Decide(ctx, r.Ctx, actor, emote, ver, approve, reason)
+
+	events.Publish(r.Ctx, "emotes", emote.ID)
+
+	loaders.For(ctx).EmoteByID.Clear(ver.ID)
+	loaders.For(ctx).EmoteByID.Clear(emote.ID)
+	return loaders.For(ctx).EmoteByID.Load(ver.ID)
+}
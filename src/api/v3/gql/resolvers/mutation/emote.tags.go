@@ -0,0 +1,152 @@
+package mutation
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/src/api/events"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RenameEmoteTag: replace a tag with another on every emote which has it
+func (r *Resolver) RenameEmoteTag(ctx context.Context, tag string, name string) (int, error) {
+	return r.MergeEmoteTags(ctx, []string{tag}, name)
+}
+
+// MergeEmoteTags: replace several tags with a single one on every emote which has any of them
+func (r *Resolver) MergeEmoteTags(ctx context.Context, tags []string, into string) (int, error) {
+	into = strings.ToLower(into)
+	if !helpers.TagRegex.MatchString(into) {
+		return 0, errors.ErrInvalidRequest().SetDetail("Bad tag: %s", into)
+	}
+	if err := r.validateEmoteTags(ctx, []string{into}); err != nil {
+		return 0, err
+	}
+
+	from := []string{}
+	for _, t := range tags {
+		if t = strings.ToLower(t); t != into {
+			from = append(from, t)
+		}
+	}
+	if len(from) == 0 {
+		return 0, errors.ErrDontBeSilly().SetDetail("Cannot merge a tag into itself")
+	}
+
+	emotes, ids, err := r.findEmotesByTags(ctx, from)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	// Done in a pipeline so that emotes which already have the target tag don't get it twice.
+	// The other tags keep their order, and the target tag is added last
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateMany(ctx, bson.M{
+		"_id": bson.M{"$in": ids},
+	}, bson.A{bson.M{"$set": bson.M{"tags": bson.M{"$concatArrays": bson.A{
+		bson.M{"$filter": bson.M{
+			"input": "$tags",
+			"as":    "t",
+			"cond":  bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$t", from}}}},
+		}},
+		bson.M{"$cond": bson.A{bson.M{"$in": bson.A{into, "$tags"}}, bson.A{}, bson.A{into}}},
+	}}}}}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to merge emote tags")
+		return 0, errors.ErrInternalServerError()
+	}
+
+	r.emoteTagsChanged(ctx, emotes)
+	return len(ids), nil
+}
+
+// BanEmoteTag: disallow a tag, removing it from every emote which has it
+func (r *Resolver) BanEmoteTag(ctx context.Context, tag string, reason *string) (int, error) {
+	actor := auth.For(ctx)
+
+	ban := &helpers.EmoteTagBan{
+		Name:     strings.ToLower(tag),
+		ActorID:  actor.ID,
+		BannedAt: time.Now(),
+	}
+	if reason != nil {
+		ban.Reason = *reason
+	}
+	if _, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteTagBans).ReplaceOne(ctx, bson.M{"_id": ban.Name}, ban, options.Replace().SetUpsert(true)); err != nil {
+		logrus.WithError(err).Error("mongo, failed to create emote tag ban")
+		return 0, errors.ErrInternalServerError()
+	}
+
+	emotes, ids, err := r.findEmotesByTags(ctx, []string{ban.Name})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateMany(ctx, bson.M{
+		"_id": bson.M{"$in": ids},
+	}, bson.M{"$pull": bson.M{"tags": ban.Name}}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to remove banned emote tag")
+		return 0, errors.ErrInternalServerError()
+	}
+
+	r.emoteTagsChanged(ctx, emotes)
+	return len(ids), nil
+}
+
+// UnbanEmoteTag: allow a previously banned tag to be used again
+func (r *Resolver) UnbanEmoteTag(ctx context.Context, tag string) (bool, error) {
+	res, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteTagBans).DeleteOne(ctx, bson.M{"_id": strings.ToLower(tag)})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to remove emote tag ban")
+		return false, errors.ErrInternalServerError()
+	}
+	if res.DeletedCount == 0 {
+		return false, errors.ErrInvalidRequest().SetDetail("This tag is not banned")
+	}
+
+	return true, nil
+}
+
+// findEmotesByTags returns the emotes which have any of the given tags, with only the IDs of their versions, and their IDs
+func (r *Resolver) findEmotesByTags(ctx context.Context, tags []string) ([]*structures.Emote, []primitive.ObjectID, error) {
+	emotes := []*structures.Emote{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{
+		"tags": bson.M{"$in": tags},
+	}, options.Find().SetProjection(bson.M{"_id": 1, "versions.id": 1}))
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emotes by tag")
+		return nil, nil, errors.ErrInternalServerError()
+	}
+
+	ids := make([]primitive.ObjectID, len(emotes))
+	for i, e := range emotes {
+		ids[i] = e.ID
+	}
+	return emotes, ids, nil
+}
+
+// emoteTagsChanged notifies of changes to the tags of emotes and invalidates the popular tags
+func (r *Resolver) emoteTagsChanged(ctx context.Context, emotes []*structures.Emote) {
+	for _, e := range emotes {
+		events.Publish(r.Ctx, "emotes", e.ID)
+
+		// Emotes are loaded by the IDs of their versions
+		for _, ver := range e.Versions {
+			loaders.For(ctx).EmoteByID.Clear(ver.ID)
+		}
+	}
+
+	_, _ = r.Ctx.Inst().Redis.Del(ctx, r.Ctx.Inst().Redis.ComposeKey("gql-v3", "emote-tags:popular"))
+}
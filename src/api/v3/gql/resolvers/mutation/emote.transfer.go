@@ -0,0 +1,201 @@
+package mutation
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/events"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const EMOTE_TRANSFER_EXPIRY = time.Hour * 24 * 7
+
+type emoteTransfer struct {
+	ID         primitive.ObjectID        `bson:"_id"`
+	EmoteID    primitive.ObjectID        `bson:"emote_id"`
+	FromUserID primitive.ObjectID        `bson:"from_user_id"`
+	ToUserID   primitive.ObjectID        `bson:"to_user_id"`
+	ActorID    primitive.ObjectID        `bson:"actor_id"`
+	Status     model.EmoteTransferStatus `bson:"status"`
+	ExpiresAt  time.Time                 `bson:"expires_at"`
+}
+
+func (t *emoteTransfer) toModel() *model.EmoteTransfer {
+	status := t.Status
+	if status == model.EmoteTransferStatusPending && time.Now().After(t.ExpiresAt) {
+		status = model.EmoteTransferStatusExpired
+	}
+
+	return &model.EmoteTransfer{
+		ID:         t.ID,
+		EmoteID:    t.EmoteID,
+		FromUserID: t.FromUserID,
+		ToUserID:   t.ToUserID,
+		Status:     status,
+		CreatedAt:  t.ID.Timestamp(),
+		ExpiresAt:  t.ExpiresAt,
+	}
+}
+
+// RequestEmoteTransfer: offer ownership of an emote to another user, who must accept it
+func (r *Resolver) RequestEmoteTransfer(ctx context.Context, emoteID primitive.ObjectID, toUserID primitive.ObjectID) (*model.EmoteTransfer, error) {
+	actor := auth.For(ctx)
+
+	emote, err := r.fetchEmote(ctx, emoteID)
+	if err != nil {
+		return nil, err
+	}
+	// Editors may manage the owner's emotes, but not give them away
+	if emote.OwnerID != actor.ID && !actor.HasPermission(structures.RolePermissionEditAnyEmote) {
+		return nil, errors.ErrInsufficientPrivilege().SetDetail("Only the owner of an emote can transfer it")
+	}
+	if emote.OwnerID == toUserID {
+		return nil, errors.ErrDontBeSilly().SetDetail("This user already owns the emote")
+	}
+
+	recipient := &structures.User{}
+	if err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{"_id": toUserID}).Decode(recipient); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownUser()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch user")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	// Only one transfer may be pending per emote; a new request supersedes the previous one
+	col := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteTransfers)
+	if _, err = col.UpdateMany(ctx, bson.M{
+		"emote_id": emote.ID,
+		"status":   model.EmoteTransferStatusPending,
+	}, bson.M{"$set": bson.M{"status": model.EmoteTransferStatusCancelled}}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to cancel previous emote transfers")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	transfer := &emoteTransfer{
+		ID:         primitive.NewObjectID(),
+		EmoteID:    emote.ID,
+		FromUserID: emote.OwnerID,
+		ToUserID:   recipient.ID,
+		ActorID:    actor.ID,
+		Status:     model.EmoteTransferStatusPending,
+		ExpiresAt:  time.Now().Add(EMOTE_TRANSFER_EXPIRY),
+	}
+	if _, err = col.InsertOne(ctx, transfer); err != nil {
+		logrus.WithError(err).Error("mongo, failed to create emote transfer")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	// Deliver the request to the recipient's inbox
+	mb := structures.NewMessageBuilder(&structures.Message{}).
+		SetKind(structures.MessageKindInbox).
+		SetAuthorID(actor.ID).
+		SetTimestamp(time.Now()).
+		AsInbox(structures.MessageDataInbox{
+			Subject:   "inbox.generic.emote_transfer_request.subject",
+			Content:   "inbox.generic.emote_transfer_request.content",
+			Important: true,
+			Placeholders: map[string]string{
+				"EMOTE_ID":    emote.ID.Hex(),
+				"EMOTE_NAME":  emote.Name,
+				"TRANSFER_ID": transfer.ID.Hex(),
+				"EXPIRES_AT":  transfer.ExpiresAt.Format(time.RFC3339),
+			},
+		})
+	mm := mutations.MessageMutation{
+		MessageBuilder: mb,
+	}
+	if _, err = mm.SendInboxMessage(ctx, r.Ctx.Inst().Mongo, mutations.SendInboxMessageOptions{
+		Actor:                actor,
+		Recipients:           []primitive.ObjectID{recipient.ID},
+		ConsiderBlockedUsers: true,
+	}); err != nil {
+		logrus.WithError(err).Error("mutation, failed to send emote transfer message")
+	}
+
+	events.Publish(r.Ctx, "users", transfer.FromUserID)
+	events.Publish(r.Ctx, "users", transfer.ToUserID)
+
+	return transfer.toModel(), nil
+}
+
+// RespondEmoteTransfer: accept or decline a pending emote transfer
+func (r *Resolver) RespondEmoteTransfer(ctx context.Context, id primitive.ObjectID, accept bool) (*model.EmoteTransfer, error) {
+	actor := auth.For(ctx)
+
+	col := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteTransfers)
+	transfer := &emoteTransfer{}
+	if err := col.FindOne(ctx, bson.M{"_id": id, "to_user_id": actor.ID}).Decode(transfer); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrInvalidRequest().SetDetail("Unknown Transfer")
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote transfer")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	switch transfer.toModel().Status {
+	case model.EmoteTransferStatusPending:
+	case model.EmoteTransferStatusExpired:
+		_, _ = col.UpdateOne(ctx, bson.M{"_id": transfer.ID}, bson.M{"$set": bson.M{"status": model.EmoteTransferStatusExpired}})
+		return nil, errors.ErrInvalidRequest().SetDetail("This transfer has expired")
+	default:
+		return nil, errors.ErrInvalidRequest().SetDetail("This transfer is no longer pending")
+	}
+
+	var emote *structures.Emote
+	status := model.EmoteTransferStatusDeclined
+	if accept {
+		var err error
+		if emote, err = r.fetchEmote(ctx, transfer.EmoteID); err != nil {
+			return nil, err
+		}
+		if emote.OwnerID != transfer.FromUserID {
+			_, _ = col.UpdateOne(ctx, bson.M{"_id": transfer.ID}, bson.M{"$set": bson.M{"status": model.EmoteTransferStatusCancelled}})
+			return nil, errors.ErrInvalidRequest().SetDetail("The emote has changed owner since this transfer was requested")
+		}
+
+		status = model.EmoteTransferStatusAccepted
+	}
+
+	// Claim the transfer, in case it is being answered concurrently
+	res, err := col.UpdateOne(ctx, bson.M{
+		"_id":    transfer.ID,
+		"status": model.EmoteTransferStatusPending,
+	}, bson.M{"$set": bson.M{"status": status}})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to update emote transfer")
+		return nil, errors.ErrInternalServerError()
+	}
+	if res.ModifiedCount == 0 {
+		return nil, errors.ErrInvalidRequest().SetDetail("This transfer is no longer pending")
+	}
+	transfer.Status = status
+
+	if emote != nil {
+		// Both parties have consented, so the edit is made without an actor
+		m := mutations.EmoteMutation{
+			EmoteBuilder: structures.NewEmoteBuilder(emote).SetOwnerID(transfer.ToUserID),
+		}
+		if _, err = m.Edit(ctx, r.Ctx.Inst().Mongo, mutations.EmoteEditOptions{}); err != nil {
+			logrus.WithError(err).Error("mutation, failed to transfer emote")
+			_, _ = col.UpdateOne(ctx, bson.M{"_id": transfer.ID}, bson.M{"$set": bson.M{"status": model.EmoteTransferStatusPending}})
+			return nil, errors.ErrInternalServerError()
+		}
+
+		events.Publish(r.Ctx, "emotes", emote.ID)
+	}
+
+	events.Publish(r.Ctx, "users", transfer.FromUserID)
+	events.Publish(r.Ctx, "users", transfer.ToUserID)
+
+	return transfer.toModel(), nil
+}
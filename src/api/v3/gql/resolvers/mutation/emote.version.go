@@ -0,0 +1,320 @@
+package mutation
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/events"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/emotes"
+	"github.com/SevenTV/GQL/src/instance"
+	"github.com/SevenTV/GQL/src/storage"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The default version of an emote is the one shown in search. Its ID is stored, and it is kept first in the list of versions.
+// Deprecated versions are flagged as such: they remain usable by the sets which have them, but cannot be made default.
+
+// CreateEmoteVersion: upload a new version of an existing emote
+func (r *Resolver) CreateEmoteVersion(ctx context.Context, emoteID primitive.ObjectID, data model.CreateEmoteVersionInput, file graphql.Upload) (*model.Emote, error) {
+	actor := auth.For(ctx)
+
+	emote, err := r.fetchEmote(ctx, emoteID)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.checkEmotePermission(ctx, actor, emote); err != nil {
+		return nil, err
+	}
+
+	name := emote.Name
+	if data.Name != nil {
+		if !helpers.EmoteNameRegex.MatchString(*data.Name) {
+			return nil, errors.ErrEmoteNameInvalid()
+		}
+		name = *data.Name
+	}
+	description := ""
+	if data.Description != nil {
+		description = *data.Description
+	}
+
+	b, meta, err := r.readEmoteUpload(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the original file
+	id := primitive.NewObjectID()
+	if err = r.Ctx.Inst().Storage.Put(ctx, storage.EmoteOriginalKey(id), b, string(meta.Format)); err != nil {
+		logrus.WithError(err).Error("storage, failed to store emote upload")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	m := mutations.EmoteMutation{
+		EmoteBuilder: structures.NewEmoteBuilder(emote).AddVersion(&structures.EmoteVersion{
+			ID:          id,
+			Name:        name,
+			Description: description,
+			Timestamp:   time.Now(),
+			FrameCount:  int32(meta.FrameCount),
+			State: structures.EmoteVersionState{
+				Lifecycle: structures.EmoteLifecyclePending,
+			},
+		}),
+	}
+	if _, err = m.Edit(ctx, r.Ctx.Inst().Mongo, mutations.EmoteEditOptions{
+		Actor: actor,
+	}); err != nil {
+		_ = r.Ctx.Inst().Storage.Delete(ctx, storage.EmoteOriginalKey(id))
+		if err == structures.ErrInsufficientPrivilege {
+			return nil, errors.ErrInsufficientPrivilege()
+		}
+		logrus.WithError(err).Error("mutation, failed to add emote version")
+		return nil, errors.ErrInternalServerError()
+	}
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(ctx, bson.M{
+		"_id":         emote.ID,
+		"versions.id": id,
+	}, bson.M{"$set": bson.M{"versions.$.review": emotes.ReviewStatePending}}); err != nil {
+		logrus.WithError(err).Error("mongo, failed to set emote version states")
+	}
+
+	if err = r.Ctx.Inst().Jobs.EnqueueEmote(ctx, instance.EmoteJob{
+		EmoteID:   emote.ID,
+		VersionID: id,
+	}); err != nil {
+		logrus.WithError(err).WithField("emote_id", emote.ID.Hex()).Error("jobs, failed to enqueue emote")
+	}
+
+	events.Publish(r.Ctx, "emotes", emote.ID)
+
+	loaders.For(ctx).EmoteByID.Clear(emote.ID)
+	return loaders.For(ctx).EmoteByID.Load(emote.ID)
+}
+
+// SetDefaultEmoteVersion: make a version the one shown in search
+func (r *Resolver) SetDefaultEmoteVersion(ctx context.Context, emoteID primitive.ObjectID, versionID primitive.ObjectID) (*model.Emote, error) {
+	actor := auth.For(ctx)
+
+	emote, ver, err := r.fetchEmoteVersion(ctx, emoteID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.checkEmotePermission(ctx, actor, emote); err != nil {
+		return nil, err
+	}
+	if ver.State.Lifecycle != structures.EmoteLifecycleLive {
+		return nil, errors.ErrInvalidRequest().SetDetail("Only live versions can be made default")
+	}
+	states, err := r.fetchEmoteVersionStates(ctx, emote)
+	if err != nil {
+		return nil, err
+	}
+	if states.IsDeprecated(ver.ID) {
+		return nil, errors.ErrInvalidRequest().SetDetail("Deprecated versions cannot be made default")
+	}
+
+	// Store the version as default and move it to the front of the list
+	// This is done in a pipeline so as not to overwrite concurrent changes to other versions
+	res, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(ctx, bson.M{
+		"_id": emote.ID,
+		"versions": bson.M{"$elemMatch": bson.M{
+			"id":         ver.ID,
+			"deprecated": bson.M{"$ne": true},
+		}},
+	}, bson.A{bson.M{"$set": bson.M{
+		"default_version_id": ver.ID,
+		"versions": bson.M{"$concatArrays": bson.A{
+			bson.M{"$filter": bson.M{"input": "$versions", "as": "v", "cond": bson.M{"$eq": bson.A{"$$v.id", ver.ID}}}},
+			bson.M{"$filter": bson.M{"input": "$versions", "as": "v", "cond": bson.M{"$ne": bson.A{"$$v.id", ver.ID}}}},
+		}},
+	}}})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to set default emote version")
+		return nil, errors.ErrInternalServerError()
+	}
+	if res.MatchedCount == 0 {
+		return nil, errors.ErrInvalidRequest().SetDetail("Deprecated versions cannot be made default")
+	}
+
+	events.Publish(r.Ctx, "emotes", emote.ID)
+
+	loaders.For(ctx).EmoteByID.Clear(emote.ID)
+	return loaders.For(ctx).EmoteByID.Load(emote.ID)
+}
+
+// DeprecateEmoteVersion: flag a version as deprecated, which will no longer be offered in place of the default
+func (r *Resolver) DeprecateEmoteVersion(ctx context.Context, emoteID primitive.ObjectID, versionID primitive.ObjectID) (*model.Emote, error) {
+	actor := auth.For(ctx)
+
+	emote, ver, err := r.fetchEmoteVersion(ctx, emoteID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.checkEmotePermission(ctx, actor, emote); err != nil {
+		return nil, err
+	}
+	if ver.State.Lifecycle != structures.EmoteLifecycleLive {
+		return nil, errors.ErrInvalidRequest().SetDetail("Only live versions can be deprecated")
+	}
+	states, err := r.fetchEmoteVersionStates(ctx, emote)
+	if err != nil {
+		return nil, err
+	}
+	if states.DefaultID() == ver.ID {
+		return nil, errors.ErrInvalidRequest().SetDetail("Set a different default version before deprecating this one")
+	}
+	if states.IsDeprecated(ver.ID) {
+		return nil, errors.ErrInvalidRequest().SetDetail("This version is already deprecated")
+	}
+
+	// The version must still not be the default when written
+	res, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(ctx, bson.M{
+		"_id":                emote.ID,
+		"versions.id":        ver.ID,
+		"default_version_id": bson.M{"$ne": ver.ID},
+	}, bson.M{"$set": bson.M{"versions.$.deprecated": true}})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to deprecate emote version")
+		return nil, errors.ErrInternalServerError()
+	}
+	if res.MatchedCount == 0 {
+		return nil, errors.ErrInvalidRequest().SetDetail("Set a different default version before deprecating this one")
+	}
+
+	events.Publish(r.Ctx, "emotes", emote.ID)
+
+	loaders.For(ctx).EmoteByID.Clear(emote.ID)
+	return loaders.For(ctx).EmoteByID.Load(emote.ID)
+}
+
+// MigrateEmoteVersion: replace one version of an emote with another in every emote set which has it
+func (r *Resolver) MigrateEmoteVersion(ctx context.Context, emoteID primitive.ObjectID, fromVersionID primitive.ObjectID, toVersionID primitive.ObjectID, dryRun *bool) (*model.EmoteVersionMigration, error) {
+	actor := auth.For(ctx)
+
+	emote, from, err := r.fetchEmoteVersion(ctx, emoteID, fromVersionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.checkEmotePermission(ctx, actor, emote); err != nil {
+		return nil, err
+	}
+	_, to, err := r.fetchEmoteVersion(ctx, emoteID, toVersionID)
+	if err != nil {
+		return nil, err
+	}
+	if from.ID == to.ID {
+		return nil, errors.ErrDontBeSilly().SetDetail("Cannot migrate a version to itself")
+	}
+	if to.State.Lifecycle != structures.EmoteLifecycleLive {
+		return nil, errors.ErrInvalidRequest().SetDetail("Can only migrate to a live version")
+	}
+	states, err := r.fetchEmoteVersionStates(ctx, emote)
+	if err != nil {
+		return nil, err
+	}
+	if states.IsDeprecated(to.ID) {
+		return nil, errors.ErrInvalidRequest().SetDetail("Cannot migrate to a deprecated version")
+	}
+
+	result := &model.EmoteVersionMigration{
+		FromVersionID:      from.ID,
+		ToVersionID:        to.ID,
+		EmoteSetIds:        []primitive.ObjectID{},
+		SkippedEmoteSetIds: []primitive.ObjectID{},
+		DryRun:             dryRun != nil && *dryRun,
+	}
+
+	// Find affected sets; those which already have the target version are left alone
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).Find(ctx, bson.M{
+		"emotes.id": from.ID,
+	}, options.Find().SetProjection(bson.M{"_id": 1, "emotes.id": 1}))
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote sets")
+		return nil, errors.ErrInternalServerError()
+	}
+	sets := []*structures.EmoteSet{}
+	if err = cur.All(ctx, &sets); err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote sets")
+		return nil, errors.ErrInternalServerError()
+	}
+	for _, set := range sets {
+		skip := false
+		for _, ae := range set.Emotes {
+			if ae.ID == to.ID {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			result.SkippedEmoteSetIds = append(result.SkippedEmoteSetIds, set.ID)
+		} else {
+			result.EmoteSetIds = append(result.EmoteSetIds, set.ID)
+		}
+	}
+	if result.DryRun || len(result.EmoteSetIds) == 0 {
+		return result, nil
+	}
+
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).UpdateMany(ctx, bson.M{
+		"_id": bson.M{"$in": result.EmoteSetIds},
+		"emotes.id": bson.M{
+			"$eq": from.ID,
+			"$ne": to.ID,
+		},
+	}, bson.M{
+		"$set": bson.M{"emotes.$[ae].id": to.ID},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: bson.A{bson.M{"ae.id": from.ID}},
+	})); err != nil {
+		logrus.WithError(err).Error("mongo, failed to migrate emote version")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	for _, setID := range result.EmoteSetIds {
+		events.Publish(r.Ctx, "emote_sets", setID)
+		loaders.For(ctx).EmoteSetByID.Clear(setID)
+	}
+
+	return result, nil
+}
+
+// fetchEmoteVersion retrieves an emote structure along with one of its versions
+func (r *Resolver) fetchEmoteVersion(ctx context.Context, emoteID primitive.ObjectID, versionID primitive.ObjectID) (*structures.Emote, *structures.EmoteVersion, error) {
+	emote, err := r.fetchEmote(ctx, emoteID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, ver := range emote.Versions {
+		if ver.ID == versionID {
+			return emote, ver, nil
+		}
+	}
+	return nil, nil, errors.ErrUnknownEmote().SetDetail("Unknown Version")
+}
+
+// fetchEmoteVersionStates retrieves which versions of an emote are default or deprecated
+func (r *Resolver) fetchEmoteVersionStates(ctx context.Context, emote *structures.Emote) (*helpers.EmoteVersionStates, error) {
+	states, err := helpers.FetchEmoteVersionStates(ctx, r.Ctx, bson.M{"_id": emote.ID})
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emote version states")
+		return nil, errors.ErrInternalServerError()
+	}
+	for _, s := range states {
+		return s, nil
+	}
+	return nil, errors.ErrUnknownEmote()
+}
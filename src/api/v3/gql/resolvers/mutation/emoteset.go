@@ -2,13 +2,21 @@ package mutation
 
 import (
 	"context"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
 	"github.com/SevenTV/Common/structures/v3"
 	"github.com/SevenTV/Common/structures/v3/mutations"
 	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/GQL/graph/model"
 	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/api/v3/gql/resolvers/emoteset"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -28,7 +36,7 @@ func (r *Resolver) CreateEmoteSet(ctx context.Context, input model.CreateEmoteSe
 		SetName(input.Name).
 		SetPrivileged(isPrivileged).
 		SetOwnerID(actor.ID).
-		SetEmoteSlots(250)
+		SetEmoteSlots(int32(helpers.EmoteSetMaxSlots(r.Ctx, nil)))
 	m := mutations.EmoteSetMutation{
 		EmoteSetBuilder: b,
 	}
@@ -42,3 +50,46 @@ func (r *Resolver) CreateEmoteSet(ctx context.Context, input model.CreateEmoteSe
 
 	return loaders.For(ctx).EmoteSetByID.Load(b.EmoteSet.ID)
 }
+
+// CloneEmoteSet: create a new emote set with the emotes of another
+func (r *Resolver) CloneEmoteSet(ctx context.Context, sourceID primitive.ObjectID, name string) (*model.EmoteSetImportResult, error) {
+	actor := auth.For(ctx)
+
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > emoteset.EMOTE_SET_NAME_MAX {
+		return nil, errors.ErrInvalidRequest().SetDetail("Name must be between 1 and %d characters", emoteset.EMOTE_SET_NAME_MAX)
+	}
+	if source, err := loaders.For(ctx).EmoteSetByID.Load(sourceID); err != nil || source == nil {
+		return nil, errors.ErrUnknownEmoteSet()
+	}
+	// The emotes are imported as an edit of the new set, which the actor must be allowed to make
+	if !actor.HasPermission(structures.RolePermissionEditEmoteSet) && !actor.HasPermission(structures.RolePermissionEditAnyEmoteSet) {
+		return nil, errors.ErrInsufficientPrivilege().SetDetail("You are not allowed to edit emote sets")
+	}
+
+	b := structures.NewEmoteSetBuilder(nil).
+		SetName(name).
+		SetOwnerID(actor.ID).
+		SetEmoteSlots(int32(helpers.EmoteSetMaxSlots(r.Ctx, nil)))
+	m := mutations.EmoteSetMutation{
+		EmoteSetBuilder: b,
+	}
+	if _, err := m.Create(ctx, r.Ctx.Inst().Mongo, mutations.EmoteSetMutationOptions{
+		Actor: actor,
+	}); err != nil {
+		return nil, err
+	}
+
+	// The new set is empty, so replacing only adds the emotes of the source
+	strategy := model.EmoteSetImportStrategyReplace
+	result, err := emoteset.NewOps(r.Resolver).ImportFrom(ctx, &model.EmoteSetOps{ID: b.EmoteSet.ID}, sourceID, &strategy)
+	if err != nil {
+		// Don't leave behind an empty set
+		if _, derr := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).DeleteOne(ctx, bson.M{"_id": b.EmoteSet.ID}); derr != nil {
+			logrus.WithError(derr).WithField("emote_set_id", b.EmoteSet.ID).Error("mongo, failed to delete cloned emote set")
+		}
+		loaders.For(ctx).EmoteSetByID.Clear(b.EmoteSet.ID)
+		return nil, err
+	}
+	return result, nil
+}
@@ -22,11 +22,6 @@ func (r *Resolver) SetUserRole(ctx context.Context, userID primitive.ObjectID, r
 	return nil, nil
 }
 
-func (r *Resolver) EditEmote(ctx context.Context, emoteID primitive.ObjectID, data model.EditEmoteInput) (*model.Emote, error) {
-	// TODO
-	return nil, nil
-}
-
 func (r *Resolver) CreateRole(ctx context.Context, data model.CreateRoleInput) (*model.Role, error) {
 	// TODO
 	return nil, nil
@@ -42,11 +37,6 @@ func (r *Resolver) DeleteRole(ctx context.Context, roleID primitive.ObjectID) (s
 	return "", nil
 }
 
-func (r *Resolver) CreateReport(ctx context.Context, data model.CreateReportInput) (*model.Report, error) {
-	// TODO
-	return nil, nil
-}
-
 func (r *Resolver) EditReport(ctx context.Context, reportID primitive.ObjectID, data model.EditReportInput) (*model.Report, error) {
 	// primitive.ObjectID
 	return nil, nil
@@ -0,0 +1,78 @@
+package mutation
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	REPORT_SUBJECT_MAX_LENGTH = 100
+	REPORT_BODY_MAX_LENGTH    = 2000
+)
+
+// CreateReport: report an emote, user or emote comment to moderators
+func (r *Resolver) CreateReport(ctx context.Context, data model.CreateReportInput) (*model.Report, error) {
+	actor := auth.For(ctx)
+
+	targetID, err := primitive.ObjectIDFromHex(data.TargetID)
+	if err != nil {
+		return nil, errors.ErrBadObjectID()
+	}
+	if data.Subject == "" || len(data.Subject) > REPORT_SUBJECT_MAX_LENGTH {
+		return nil, errors.ErrInvalidRequest().SetDetail("Subject must be between 1 and %d characters", REPORT_SUBJECT_MAX_LENGTH)
+	}
+	if len(data.Body) > REPORT_BODY_MAX_LENGTH {
+		return nil, errors.ErrInvalidRequest().SetDetail("Body too long (max %d characters)", REPORT_BODY_MAX_LENGTH)
+	}
+
+	// Make sure the target exists
+	var (
+		col      mongo.CollectionName
+		filter   bson.M
+		notFound errors.APIError
+	)
+	switch data.TargetKind {
+	case model.TargetKindEmote:
+		col, filter, notFound = mongo.CollectionNameEmotes, bson.M{"$or": bson.A{bson.M{"_id": targetID}, bson.M{"versions.id": targetID}}}, errors.ErrUnknownEmote()
+	case model.TargetKindUser:
+		col, filter, notFound = mongo.CollectionNameUsers, bson.M{"_id": targetID}, errors.ErrUnknownUser()
+	case model.TargetKindEmoteComment:
+		col, filter, notFound = mongo.CollectionNameMessages, bson.M{"_id": targetID, "kind": structures.MessageKindEmoteComment}, errors.ErrUnknownMessage()
+	default:
+		return nil, errors.ErrInvalidRequest().SetDetail("Unknown target kind")
+	}
+	count, err := r.Ctx.Inst().Mongo.Collection(col).CountDocuments(ctx, filter)
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch report target")
+		return nil, errors.ErrInternalServerError()
+	}
+	if count == 0 {
+		return nil, notFound
+	}
+
+	rb := structures.NewReportBuilder(&structures.Report{ID: primitive.NewObjectID()}).
+		SetTargetKind(structures.ReportTargetKind(data.TargetKind)).
+		SetTargetID(targetID).
+		SetReporterID(actor.ID).
+		SetSubject(data.Subject).
+		SetBody(data.Body).
+		SetPriority(0).
+		SetStatus(structures.ReportStatusOpen).
+		SetCreatedAt(time.Now())
+	if _, err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameReports).InsertOne(ctx, rb.Report); err != nil {
+		logrus.WithError(err).Error("mongo, failed to create report")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	return helpers.ReportStructureToModel(r.Ctx, rb.Report), nil
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,25 +16,81 @@ import (
 	"github.com/SevenTV/Common/structures/v3/aggregations"
 	"github.com/SevenTV/Common/utils"
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
 	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/SevenTV/GQL/src/instance"
 	"github.com/go-redis/redis/v8"
 	"github.com/hashicorp/go-multierror"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-const EMOTES_QUERY_LIMIT = 300
+const (
+	EMOTES_QUERY_LIMIT    = 300
+	EMOTES_TRENDING_LIMIT = 1000
+)
 
 func (r *Resolver) Emote(ctx context.Context, id primitive.ObjectID) (*model.Emote, error) {
 	emote, err := loaders.For(ctx).EmoteByID.Load(id)
 	if emote == nil || emote.ID == structures.DeletedEmote.ID {
 		return nil, errors.ErrUnknownEmote()
 	}
+
+	// Deleted emotes remain visible to moderators until they are purged
+	if emote.Lifecycle == int(structures.EmoteLifecycleDeleted) {
+		actor := auth.For(ctx)
+		if actor == nil || !actor.HasPermission(structures.RolePermissionEditAnyEmote) {
+			return nil, errors.ErrUnknownEmote()
+		}
+	}
+	if !auth.CanSeeEmote(ctx, emote) {
+		return nil, errors.ErrUnknownEmote()
+	}
 	return emote, err
 }
 
+func (r *Resolver) DeletedEmotes(ctx context.Context, pageArg *int, limitArg *int) ([]*model.DeletedEmote, error) {
+	limit := 20
+	if limitArg != nil && *limitArg > 0 {
+		limit = *limitArg
+	}
+	if limit > EMOTES_QUERY_LIMIT {
+		limit = EMOTES_QUERY_LIMIT
+	}
+	page := 1
+	if pageArg != nil && *pageArg > 1 {
+		page = *pageArg
+	}
+
+	cur, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteDeletions).Find(ctx, bson.M{
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, options.Find().
+		SetSort(bson.M{"deleted_at": -1}).
+		SetSkip(int64((page-1)*limit)).
+		SetLimit(int64(limit)),
+	)
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch deleted emotes")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	deletions := []*helpers.EmoteDeletion{}
+	if err = cur.All(ctx, &deletions); err != nil {
+		logrus.WithError(err).Error("mongo, failed to decode deleted emotes")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	result := make([]*model.DeletedEmote, len(deletions))
+	for i, d := range deletions {
+		result[i] = helpers.EmoteDeletionToModel(d)
+	}
+	return result, nil
+}
+
 func (r *Resolver) Emotes(ctx context.Context, query string, pageArg *int, limitArg *int, filterArg *model.EmoteSearchFilter, sortArg *model.Sort) (*model.EmoteSearchResult, error) {
 	// Define limit (how many emotes can be returned in a single query)
 	limit := 20
@@ -45,6 +101,167 @@ func (r *Resolver) Emotes(ctx context.Context, query string, pageArg *int, limit
 		limit = EMOTES_QUERY_LIMIT
 	}
 
+	// Retrieve pagination values
+	page := 1
+	if pageArg != nil {
+		page = *pageArg
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	// Prefer the search index, falling back to the database for searches it can't answer
+	if idx := r.Ctx.Inst().Search; idx != nil && idx.Ready() {
+		if result, ok, err := r.searchEmoteIndex(ctx, idx, query, page, limit, filterArg, sortArg); ok || err != nil {
+			return result, err
+		}
+	}
+
+	search, err := r.prepareEmoteSearch(ctx, query, filterArg, sortArg)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := search.pipeline
+
+	// Complete the pipeline
+	queryKey := r.Ctx.Inst().Redis.ComposeKey("gql-v3", fmt.Sprintf("emote-search:%s", search.hash))
+	totalCount, countErr := r.Ctx.Inst().Redis.RawClient().Get(ctx, string(queryKey)).Int()
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	if countErr == redis.Nil {
+		go func() { // Run a separate pipeline to return the total count that could be paginated
+			defer wg.Done()
+			cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, aggregations.Combine(
+				pipeline,
+				mongo.Pipeline{
+					{{Key: "$count", Value: "count"}},
+				}),
+			)
+			result := make(map[string]int, 1)
+			if err == nil {
+				cur.Next(ctx)
+				if err = multierror.Append(cur.Decode(&result), cur.Close(ctx)).ErrorOrNil(); err != nil {
+					logrus.WithError(err).Error("mongo, couldn't count")
+				}
+			}
+
+			// Return total count & cache
+			totalCount = result["count"]
+			dur := utils.Ternary(search.query == "", time.Minute*10, time.Hour*1).(time.Duration)
+			if err = r.Ctx.Inst().Redis.SetEX(ctx, queryKey, totalCount, dur); err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{
+					"key":   queryKey,
+					"count": totalCount,
+				}).Error("redis, failed to save total list count of emotes() gql query")
+			}
+		}()
+	} else {
+		wg.Done()
+	}
+
+	// Paginate and fetch the relevant emotes
+	result := []*structures.Emote{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, aggregations.Combine(
+		pipeline,
+		mongo.Pipeline{
+			{{Key: "$sort", Value: bson.D{{Key: helpers.CursorSortKey, Value: search.order}, {Key: "_id", Value: search.order}}}},
+			{{Key: "$skip", Value: (page - 1) * limit}},
+			{{Key: "$limit", Value: limit}},
+		},
+		aggregations.GetEmoteRelationshipOwner(aggregations.UserRelationshipOptions{Roles: true}),
+	))
+	if err == nil {
+		if err = cur.All(ctx, &result); err != nil {
+			logrus.WithError(err).Error("mongo, failed to fetch emotes")
+		}
+	}
+	wg.Wait() // wait for total count to finish
+
+	models := make([]*model.Emote, len(result))
+	for i, e := range result {
+		// Bring forward the default version
+		if len(e.Versions) > 0 {
+			e.ID = e.Versions[0].ID
+		}
+		models[i] = helpers.EmoteStructureToModel(r.Ctx, e)
+	}
+
+	return &model.EmoteSearchResult{
+		Count: totalCount,
+		Items: models,
+	}, nil
+}
+
+func (r *Resolver) EmotesConnection(ctx context.Context, query string, firstArg *int, after *string, filterArg *model.EmoteSearchFilter, sortArg *model.Sort) (*model.EmoteConnection, error) {
+	first := 20
+	if firstArg != nil {
+		first = *firstArg
+	}
+	if first > EMOTES_QUERY_LIMIT {
+		first = EMOTES_QUERY_LIMIT
+	} else if first < 1 {
+		return nil, errors.ErrInvalidRequest().SetDetail("first cannot be less than 1")
+	}
+
+	search, err := r.prepareEmoteSearch(ctx, query, filterArg, sortArg)
+	if err != nil {
+		return nil, err
+	}
+	page, err := helpers.CursorPipeline(after, first, search.order)
+	if err != nil {
+		return nil, errors.ErrInvalidRequest().SetDetail(err.Error())
+	}
+
+	items := []bson.Raw{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, aggregations.Combine(search.pipeline, page))
+	if err == nil {
+		err = cur.All(ctx, &items)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emotes")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	items, cursors, info, err := helpers.CursorEdges(items, first, after)
+	if err != nil {
+		logrus.WithError(err).Error("failed to encode cursors")
+		return nil, errors.ErrInternalServerError()
+	}
+	edges := make([]*model.EmoteEdge, 0, len(items))
+	for i, item := range items {
+		e := &structures.Emote{}
+		if err = bson.Unmarshal(item, e); err != nil {
+			logrus.WithError(err).Error("mongo, failed to decode emote")
+			continue
+		}
+
+		// Bring forward the default version
+		if len(e.Versions) > 0 {
+			e.ID = e.Versions[0].ID
+		}
+		edges = append(edges, &model.EmoteEdge{
+			Cursor: cursors[i],
+			Node:   helpers.EmoteStructureToModel(r.Ctx, e),
+		})
+	}
+
+	return &model.EmoteConnection{
+		Edges:    edges,
+		PageInfo: info,
+	}, nil
+}
+
+// emoteSearch is a prepared emote search, shared by the page and cursor based queries
+type emoteSearch struct {
+	query string
+	// The stages matching emotes and setting the key they should be sorted by
+	pipeline mongo.Pipeline
+	order    int32
+	// Identifies the search for caching its total count of results
+	hash string
+}
+
+func (r *Resolver) prepareEmoteSearch(ctx context.Context, query string, filterArg *model.EmoteSearchFilter, sortArg *model.Sort) (*emoteSearch, error) {
 	// Define default filter
 	filter := filterArg
 	if filter == nil {
@@ -52,46 +269,114 @@ func (r *Resolver) Emotes(ctx context.Context, query string, pageArg *int, limit
 			CaseSensitive: utils.BoolPointer(false),
 			ExactMatch:    utils.BoolPointer(false),
 		}
-	} else {
-		filter = filterArg
 	}
 
 	// Define the query string
 	query = strings.Trim(query, " ")
 
-	// Retrieve pagination values
-	page := 1
-	if pageArg != nil {
-		page = *pageArg
-	}
-	if page < 1 {
-		page = 1
-	}
-
 	// Retrieve sorting options
 	sortopt := &model.Sort{
 		Value: "popularity",
-		Order: model.SortOrderAscending,
+		Order: model.SortOrderDescending,
 	}
 	if sortArg != nil {
 		sortopt = sortArg
 	}
 
+	ownerID := filter.OwnerID
+	if ownerID == nil && filter.OwnerUsername != nil {
+		id, err := r.findUserIDByUsername(ctx, *filter.OwnerUsername)
+		if err != nil {
+			return nil, err
+		}
+		ownerID = &id
+	}
+
 	// Set up db query
 	match := bson.D{{Key: "versions.0.state.lifecycle", Value: structures.EmoteLifecycleLive}}
 
-	// Define the pipeline
-	pipeline := mongo.Pipeline{}
+	// Private and unlisted emotes are left out,
+	// unless searching among the emotes of a user whose private emotes the actor can see
+	includeHidden := r.includeHiddenEmotes(ctx, ownerID)
+	if !includeHidden {
+		match = append(match, listedEmotesFilter...)
+	}
 
 	// Define sorting
 	// (will be ignored in the case of exact search)
 	order, validOrder := sortOrderMap[string(sortopt.Order)]
-	field, validField := sortFieldMap[sortopt.Value]
+	sortKey, validField := sortFieldMap[sortopt.Value]
+	if !validOrder || !validField {
+		sortKey, order = "$_id", -1
+	}
+
+	// Apply filters
+	if filter.Animated != nil {
+		if *filter.Animated {
+			match = append(match, bson.E{Key: "versions.0.frame_count", Value: bson.M{"$gt": 1}})
+		} else {
+			match = append(match, bson.E{Key: "versions.0.frame_count", Value: bson.M{"$lte": 1}})
+		}
+	}
+	if filter.ZeroWidth != nil {
+		op := utils.Ternary(*filter.ZeroWidth, "$bitsAllSet", "$bitsAllClear").(string)
+		match = append(match, bson.E{Key: "flags", Value: bson.M{op: structures.EmoteFlagsZeroWidth}})
+	}
+	if ownerID != nil {
+		match = append(match, bson.E{Key: "owner_id", Value: *ownerID}) // no results if the user doesn't exist
+	}
+	if filter.MinChannels != nil && *filter.MinChannels > 0 {
+		match = append(match, bson.E{Key: "$expr", Value: bson.M{
+			"$gte": bson.A{bson.M{"$sum": "$versions.state.channel_count"}, *filter.MinChannels},
+		}})
+	}
+	if filter.CreatedAfter != nil || filter.CreatedBefore != nil {
+		created := bson.M{}
+		if filter.CreatedAfter != nil {
+			created["$gte"] = primitive.NewObjectIDFromTimestamp(*filter.CreatedAfter)
+		}
+		if filter.CreatedBefore != nil {
+			created["$lt"] = primitive.NewObjectIDFromTimestamp(*filter.CreatedBefore)
+		}
+		match = append(match, bson.E{Key: "_id", Value: created})
+	}
+
+	// Apply category
+	category := model.EmoteSearchCategoryAll
+	if filter.Category != nil {
+		category = *filter.Category
+	}
+	switch category {
+	case model.EmoteSearchCategoryGlobal:
+		match = append(match, bson.E{Key: "versions.id", Value: bson.M{"$in": r.globalEmoteIDs(ctx)}})
+	case model.EmoteSearchCategoryTrending:
+		trending, err := r.Ctx.Inst().Trending.Ranking(ctx, EMOTES_TRENDING_LIMIT)
+		if err != nil {
+			logrus.WithError(err).Error("trending, failed to fetch ranking")
+			return nil, errors.ErrInternalServerError()
+		}
+		match = append(match, bson.E{Key: "versions.id", Value: bson.M{"$in": trending}})
+
+		// Trending emotes are ordered by their rank, taking the best of any of their versions
+		sortKey = bson.M{"$min": bson.M{"$filter": bson.M{
+			"input": bson.M{"$map": bson.M{
+				"input": "$versions.id",
+				"in":    bson.M{"$indexOfArray": bson.A{trending, "$$this"}},
+			}},
+			"cond": bson.M{"$gte": bson.A{"$$this", 0}},
+		}}}
+		order = 1
+	}
 
 	// Apply name/tag query
 	h := sha256.New()
 	h.Write(utils.S2B(query))
-	queryKey := r.Ctx.Inst().Redis.ComposeKey("gql-v3", fmt.Sprintf("emote-search:%s", hex.EncodeToString((h.Sum(nil)))))
+	if b, err := json.Marshal(filter); err == nil {
+		h.Write(b)
+	}
+	if includeHidden {
+		h.Write([]byte("hidden"))
+	}
 	cpargs := bson.A{}
 
 	// Handle exact match
@@ -102,10 +387,9 @@ func (r *Resolver) Emotes(ctx context.Context, query string, pageArg *int, limit
 			"$search":        query,
 			"$caseSensitive": filter.CaseSensitive != nil && *filter.CaseSensitive,
 		}})
-		pipeline = append(pipeline, []bson.D{
-			{{Key: "$match", Value: match}},
-			{{Key: "$sort", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}},
-		}...)
+		if category != model.EmoteSearchCategoryTrending {
+			sortKey, order = bson.M{"$meta": "textScore"}, -1
+		}
 	} else {
 		or := bson.A{}
 		if filter.CaseSensitive != nil && *filter.CaseSensitive {
@@ -137,90 +421,164 @@ func (r *Resolver) Emotes(ctx context.Context, query string, pageArg *int, limit
 		}
 
 		match = append(match, bson.E{Key: "$or", Value: or})
-		if validOrder && validField {
-			pipeline = append(pipeline, bson.D{
-				{Key: "$sort", Value: bson.M{field: order}},
-			})
-		}
-		pipeline = append(pipeline, bson.D{{Key: "$match", Value: match}})
-	}
 
-	// Complete the pipeline
-	totalCount, countErr := r.Ctx.Inst().Redis.RawClient().Get(ctx, string(queryKey)).Int()
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	if countErr == redis.Nil {
-		go func() { // Run a separate pipeline to return the total count that could be paginated
-			defer wg.Done()
-			cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, aggregations.Combine(
-				pipeline,
-				mongo.Pipeline{
-					{{Key: "$count", Value: "count"}},
-				}),
-			)
-			result := make(map[string]int, 1)
-			if err == nil {
-				cur.Next(ctx)
-				if err = multierror.Append(cur.Decode(&result), cur.Close(ctx)).ErrorOrNil(); err != nil {
-					logrus.WithError(err).Error("mongo, couldn't count")
-				}
+		// Rank exact name matches first, then those starting with the query, then the rest
+		if query != "" && category != model.EmoteSearchCategoryTrending {
+			name := cpargs[0]
+			q := cpargs[1]
+			exact, prefix, other := 2, 1, 0
+			if order > 0 {
+				exact, prefix, other = 0, 1, 2
 			}
 
-			// Return total count & cache
-			totalCount = result["count"]
-			dur := utils.Ternary(query == "", time.Minute*10, time.Hour*1).(time.Duration)
-			if err = r.Ctx.Inst().Redis.SetEX(ctx, queryKey, totalCount, dur); err != nil {
-				logrus.WithError(err).WithFields(logrus.Fields{
-					"key":   queryKey,
-					"count": totalCount,
-				}).Error("redis, failed to save total list count of emotes() gql query")
+			// Embedded documents are compared field by field, so the order of the fields must be fixed
+			sortKey = bson.D{
+				{Key: "relevance", Value: bson.M{"$switch": bson.M{
+					"branches": bson.A{
+						bson.M{"case": bson.M{"$eq": bson.A{name, q}}, "then": exact},
+						bson.M{"case": bson.M{"$eq": bson.A{bson.M{"$indexOfCP": cpargs}, 0}}, "then": prefix},
+					},
+					"default": other,
+				}}},
+				{Key: "value", Value: sortKey},
 			}
-		}()
-	} else {
-		wg.Done()
+		}
 	}
 
-	// Paginate and fetch the relevant emotes
-	result := []*structures.Emote{}
-	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, aggregations.Combine(
-		pipeline,
-		mongo.Pipeline{
-			{{Key: "$skip", Value: (page - 1) * limit}},
-			{{Key: "$limit", Value: limit}},
+	return &emoteSearch{
+		query: query,
+		pipeline: mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$addFields", Value: bson.M{helpers.CursorSortKey: sortKey}}},
 		},
-		aggregations.GetEmoteRelationshipOwner(aggregations.UserRelationshipOptions{Roles: true}),
-	))
-	if err == nil {
-		if err = cur.All(ctx, &result); err != nil {
-			logrus.WithError(err).Error("mongo, failed to fetch emotes")
-		}
+		order: order,
+		hash:  hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// listedEmotesFilter matches emotes which are neither private nor unlisted (pending approval)
+var listedEmotesFilter = bson.D{
+	{Key: "flags", Value: bson.M{"$bitsAllClear": structures.EmoteFlagsPrivate}},
+	{Key: "versions.0.state.listed", Value: true},
+}
+
+// includeHiddenEmotes returns whether a listing of emotes, optionally those of one user, should include private and unlisted emotes
+func (r *Resolver) includeHiddenEmotes(ctx context.Context, ownerID *primitive.ObjectID) bool {
+	return auth.CanSeeHiddenEmotes(ctx) || (ownerID != nil && auth.CanSeePrivateEmotesOf(ctx, *ownerID))
+}
+
+// sortFieldMap maps the sort values accepted by emote search to the expressions they order by
+var sortFieldMap = map[string]interface{}{
+	"name":          bson.M{"$toLower": "$name"},
+	"channel_count": bson.M{"$sum": "$versions.state.channel_count"},
+	"created_at":    "$_id",
+
+	// Aliases
+	"age":        "$_id",
+	"popularity": bson.M{"$sum": "$versions.state.channel_count"},
+}
+
+// searchEmoteIndex answers an emote search using the search index
+//
+// ok is false if the search uses features the index doesn't support, in which case the database should be queried instead
+func (r *Resolver) searchEmoteIndex(ctx context.Context, idx instance.SearchIndex, query string, page int, limit int, filter *model.EmoteSearchFilter, sortArg *model.Sort) (result *model.EmoteSearchResult, ok bool, err error) {
+	opt := instance.EmoteSearchOptions{
+		Query:      strings.Trim(query, " "),
+		Sort:       "channel_count",
+		Descending: true,
+		Offset:     (page - 1) * limit,
+		Limit:      limit,
 	}
-	wg.Wait() // wait for total count to finish
 
-	models := make([]*model.Emote, len(result))
-	for i, e := range result {
-		// Sort by version timestamp
-		sort.Slice(e.Versions, func(i, j int) bool {
-			a := e.Versions[i]
-			b := e.Versions[j]
+	if filter != nil {
+		if (filter.ExactMatch != nil && *filter.ExactMatch) || (filter.Category != nil && *filter.Category == model.EmoteSearchCategoryTrending) {
+			return nil, false, nil
+		}
 
-			return b.Timestamp.Before(a.Timestamp)
-		})
+		opt.CaseSensitive = filter.CaseSensitive != nil && *filter.CaseSensitive
+		opt.IgnoreTags = filter.IgnoreTags != nil && *filter.IgnoreTags
+		opt.Animated = filter.Animated
+		opt.ZeroWidth = filter.ZeroWidth
+		opt.OwnerID = filter.OwnerID
+		opt.CreatedAfter = filter.CreatedAfter
+		opt.CreatedBefore = filter.CreatedBefore
+		if filter.MinChannels != nil {
+			opt.MinChannels = *filter.MinChannels
+		}
+		if filter.OwnerID == nil && filter.OwnerUsername != nil {
+			ownerID, err := r.findUserIDByUsername(ctx, *filter.OwnerUsername)
+			if err != nil {
+				return nil, true, err
+			}
+			opt.OwnerID = &ownerID
+		}
+		if filter.Category != nil && *filter.Category == model.EmoteSearchCategoryGlobal {
+			opt.VersionIDs = r.globalEmoteIDs(ctx)
+		}
+	}
 
-		// Bring forward the latest version
-		if len(e.Versions) > 0 {
-			e.ID = e.Versions[0].ID
+	if sortArg != nil {
+		order, validOrder := sortOrderMap[string(sortArg.Order)]
+		_, validField := sortFieldMap[sortArg.Value]
+		if validOrder && validField {
+			opt.Sort = sortArg.Value
+			opt.Descending = order < 0
+		} else {
+			opt.Sort, opt.Descending = "created_at", true
 		}
-		models[i] = helpers.EmoteStructureToModel(r.Ctx, e)
 	}
 
+	opt.IncludeHidden = r.includeHiddenEmotes(ctx, opt.OwnerID)
+
+	ids, total, err := idx.SearchEmotes(ctx, opt)
+	if err != nil {
+		logrus.WithError(err).Error("search, failed to search emotes")
+		return nil, false, nil
+	}
+
+	emotes, errs := loaders.For(ctx).EmoteByID.LoadAll(ids)
 	return &model.EmoteSearchResult{
-		Count: totalCount,
-		Items: models,
-	}, nil
+		Count: total,
+		Items: emotes,
+	}, true, multierror.Append(nil, errs...).ErrorOrNil()
+}
+
+// findUserIDByUsername returns the ID of the user with the given username, or a zero ID if there is none
+func (r *Resolver) findUserIDByUsername(ctx context.Context, username string) (primitive.ObjectID, error) {
+	user := &structures.User{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+		"username": strings.ToLower(username),
+	}, options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(user); err != nil && err != mongo.ErrNoDocuments {
+		logrus.WithError(err).Error("mongo, failed to fetch user")
+		return primitive.NilObjectID, errors.ErrInternalServerError()
+	}
+
+	return user.ID, nil
 }
 
-var sortFieldMap = map[string]string{
-	"age":        "_id",
-	"popularity": "versions.state.channel_count",
+// globalEmoteIDs returns the IDs of the emotes in the global emote set
+func (r *Resolver) globalEmoteIDs(ctx context.Context) []primitive.ObjectID {
+	emoteIDs := []primitive.ObjectID{}
+	if set := r.fetchGlobalEmoteSet(ctx); set != nil {
+		for _, ae := range set.Emotes {
+			emoteIDs = append(emoteIDs, ae.ID)
+		}
+	}
+
+	return emoteIDs
+}
+
+// fetchGlobalEmoteSet retrieves the system's global emote set
+func (r *Resolver) fetchGlobalEmoteSet(ctx context.Context) *structures.EmoteSet {
+	sys := r.Ctx.Inst().Mongo.System(ctx)
+	if sys.EmoteSetID.IsZero() {
+		return nil
+	}
+
+	set := &structures.EmoteSet{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmoteSets).FindOne(ctx, bson.M{"_id": sys.EmoteSetID}).Decode(set); err != nil {
+		logrus.WithError(err).Error("mongo, couldn't fetch the global emote set")
+		return nil
+	}
+	return set
 }
@@ -4,17 +4,23 @@ import (
 	"context"
 
 	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
 	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/aggregations"
 	"github.com/SevenTV/GQL/graph/generated"
 	"github.com/SevenTV/GQL/graph/model"
 	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
 	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	"github.com/SevenTV/GQL/src/api/v3/gql/types"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const REPORTS_QUERY_LIMIT = 100
+
 type Resolver struct {
 	types.Resolver
 }
@@ -41,6 +47,24 @@ func (r *Resolver) User(ctx context.Context, id primitive.ObjectID) (*model.User
 	return user, err
 }
 
+func (r *Resolver) UserByConnection(ctx context.Context, platform model.ConnectionPlatform, id string) (*model.User, error) {
+	user := &structures.User{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).FindOne(ctx, bson.M{
+		"connections": bson.M{"$elemMatch": bson.M{
+			"platform": platform,
+			"id":       id,
+		}},
+	}, options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownUser()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch user by connection")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	return r.User(ctx, user.ID)
+}
+
 func (r *Resolver) Users(ctx context.Context, query string) ([]*model.User, error) {
 	// TODO
 	return nil, nil
@@ -66,6 +90,64 @@ func (r *Resolver) Reports(ctx context.Context, status *model.ReportStatus, limi
 	return nil, nil
 }
 
+func (r *Resolver) ReportsConnection(ctx context.Context, status *model.ReportStatus, firstArg *int, after *string) (*model.ReportConnection, error) {
+	first := REPORTS_QUERY_LIMIT
+	if firstArg != nil && *firstArg > 0 && *firstArg < first {
+		first = *firstArg
+	}
+
+	match := bson.M{}
+	if status != nil {
+		match["status"] = *status
+	}
+
+	// Newest reports first
+	page, err := helpers.CursorPipeline(after, first, -1)
+	if err != nil {
+		return nil, errors.ErrInvalidRequest().SetDetail(err.Error())
+	}
+
+	items := []bson.Raw{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameReports).Aggregate(ctx, aggregations.Combine(
+		mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$addFields", Value: bson.M{helpers.CursorSortKey: "$_id"}}},
+		},
+		page,
+	))
+	if err == nil {
+		err = cur.All(ctx, &items)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch reports")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	items, cursors, info, err := helpers.CursorEdges(items, first, after)
+	if err != nil {
+		logrus.WithError(err).Error("failed to encode cursors")
+		return nil, errors.ErrInternalServerError()
+	}
+	edges := make([]*model.ReportEdge, 0, len(items))
+	for i, item := range items {
+		report := &structures.Report{}
+		if err = bson.Unmarshal(item, report); err != nil {
+			logrus.WithError(err).Error("mongo, failed to decode report")
+			continue
+		}
+
+		edges = append(edges, &model.ReportEdge{
+			Cursor: cursors[i],
+			Node:   helpers.ReportStructureToModel(r.Ctx, report),
+		})
+	}
+
+	return &model.ReportConnection{
+		Edges:    edges,
+		PageInfo: info,
+	}, nil
+}
+
 func (r *Resolver) Report(ctx context.Context, id primitive.ObjectID) (*model.Report, error) {
 	return loaders.For(ctx).ReportByID.Load(id)
 }
@@ -88,6 +170,6 @@ var (
 )
 
 var sortOrderMap = map[string]int32{
-	string(SortOrderDescending): 1,
-	string(SortOrderAscending):  -1,
+	string(SortOrderAscending):  1,
+	string(SortOrderDescending): -1,
 }
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/emotes"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const MODERATION_QUEUE_LIMIT = 100
+
+// ModerationQueue lists the emotes with a version which is processed and awaits review
+func (r *Resolver) ModerationQueue(ctx context.Context, kind *model.ModerationQueueKind, firstArg *int, after *string) (*model.EmoteConnection, error) {
+	first := 20
+	if firstArg != nil {
+		first = *firstArg
+	}
+	if first > MODERATION_QUEUE_LIMIT {
+		first = MODERATION_QUEUE_LIMIT
+	} else if first < 1 {
+		return nil, errors.ErrInvalidRequest().SetDetail("first cannot be less than 1")
+	}
+
+	match := bson.M{"versions.review": emotes.ReviewStatePending}
+	if kind != nil {
+		switch *kind {
+		case model.ModerationQueueKindNew:
+			match["versions.state.listed"] = bson.M{"$ne": true}
+		case model.ModerationQueueKindUpdate:
+			match["versions.state.listed"] = true
+		}
+	}
+
+	page, err := helpers.CursorPipeline(after, first, 1)
+	if err != nil {
+		return nil, errors.ErrInvalidRequest().SetDetail(err.Error())
+	}
+	pipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		// Take the first of the versions awaiting review which finished processing
+		{{Key: "$addFields", Value: bson.M{"pending": bson.M{"$arrayElemAt": bson.A{
+			bson.M{"$filter": bson.M{"input": "$versions", "as": "v", "cond": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$$v.review", emotes.ReviewStatePending}},
+				bson.M{"$eq": bson.A{"$$v.state.lifecycle", structures.EmoteLifecycleLive}},
+			}}}},
+			0,
+		}}}}},
+		{{Key: "$match", Value: bson.M{"pending": bson.M{"$exists": true}}}},
+		// Oldest uploads are the first in line
+		{{Key: "$addFields", Value: bson.M{helpers.CursorSortKey: bson.M{"$toDate": "$pending.id"}}}},
+	}, page...)
+
+	items := []bson.Raw{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, pipeline)
+	if err == nil {
+		err = cur.All(ctx, &items)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch moderation queue")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	items, cursors, info, err := helpers.CursorEdges(items, first, after)
+	if err != nil {
+		logrus.WithError(err).Error("failed to encode cursors")
+		return nil, errors.ErrInternalServerError()
+	}
+	edges := make([]*model.EmoteEdge, 0, len(items))
+	for i, item := range items {
+		e := &structures.Emote{}
+		if err = bson.Unmarshal(item, e); err != nil {
+			logrus.WithError(err).Error("mongo, failed to decode emote")
+			continue
+		}
+
+		// Bring forward the version awaiting review
+		if id, ok := item.Lookup("pending", "id").ObjectIDOK(); ok {
+			e.ID = id
+		}
+		edges = append(edges, &model.EmoteEdge{
+			Cursor: cursors[i],
+			Node:   helpers.EmoteStructureToModel(r.Ctx, e),
+		})
+	}
+
+	return &model.EmoteConnection{
+		Edges:    edges,
+		PageInfo: info,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package query
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
+	"github.com/SevenTV/GQL/src/emotes"
+	"github.com/SevenTV/GQL/src/images"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Beyond this, similar emotes may share no band with the searched one and be missed
+	SIMILAR_EMOTES_MAX_THRESHOLD = images.PHASH_MAX_THRESHOLD
+	SIMILAR_EMOTES_QUERY_LIMIT   = 100
+)
+
+func (r *Resolver) SimilarEmotes(ctx context.Context, emoteID primitive.ObjectID, thresholdArg *int) ([]*model.SimilarEmote, error) {
+	threshold := images.PHASH_SIMILARITY_THRESHOLD
+	if thresholdArg != nil {
+		threshold = *thresholdArg
+	}
+	if threshold < 0 || threshold > SIMILAR_EMOTES_MAX_THRESHOLD {
+		return nil, errors.ErrInvalidRequest().SetDetail("threshold must be between 0 and %d", SIMILAR_EMOTES_MAX_THRESHOLD)
+	}
+
+	emote := &emotes.Hashes{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"$or": bson.A{bson.M{"_id": emoteID}, bson.M{"versions.id": emoteID}},
+	}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmote()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	hashes := []int64{}
+	for _, ver := range emote.Versions {
+		hashes = append(hashes, ver.Hashes...)
+	}
+
+	similar, err := emotes.FindSimilarVersions(ctx, r.Ctx, hashes, emote.ID, threshold)
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to find similar emotes")
+		return nil, errors.ErrInternalServerError()
+	}
+	if len(similar) > SIMILAR_EMOTES_QUERY_LIMIT {
+		similar = similar[:SIMILAR_EMOTES_QUERY_LIMIT]
+	}
+
+	// Bring forward the similar versions
+	ids := make([]primitive.ObjectID, len(similar))
+	for i, s := range similar {
+		ids[i] = s.VersionID
+	}
+	models, errs := loaders.For(ctx).EmoteByID.LoadAll(ids)
+
+	result := make([]*model.SimilarEmote, 0, len(similar))
+	for i, s := range similar {
+		if errs[i] != nil || models[i] == nil {
+			continue
+		}
+		result = append(result, &model.SimilarEmote{
+			Emote:    models[i],
+			Distance: s.Distance,
+		})
+	}
+	return result, nil
+}
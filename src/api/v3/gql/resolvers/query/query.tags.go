@@ -0,0 +1,204 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/aggregations"
+	"github.com/SevenTV/Common/utils"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	EMOTE_TAGS_QUERY_LIMIT = 100
+	// How many of the most used tags are cached
+	EMOTE_TAGS_POPULAR_COUNT = 1000
+	EMOTE_TAGS_POPULAR_TTL   = time.Hour
+)
+
+func (r *Resolver) EmoteTags(ctx context.Context, prefixArg *string, limitArg *int) ([]*model.EmoteTag, error) {
+	limit := 10
+	if limitArg != nil && *limitArg > 0 {
+		limit = *limitArg
+	}
+	if limit > EMOTE_TAGS_QUERY_LIMIT {
+		limit = EMOTE_TAGS_QUERY_LIMIT
+	}
+	prefix := ""
+	if prefixArg != nil {
+		prefix = strings.ToLower(strings.TrimSpace(*prefixArg))
+	}
+
+	popular, err := r.popularEmoteTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*model.EmoteTag{}
+	for _, t := range popular {
+		if strings.HasPrefix(t.Name, prefix) {
+			result = append(result, t)
+			if len(result) == limit {
+				return result, nil
+			}
+		}
+	}
+	if prefix == "" || len(popular) < EMOTE_TAGS_POPULAR_COUNT {
+		return result, nil // the popular tags are all there is
+	}
+
+	// Less used tags aren't cached, so query them directly
+	return r.aggregateEmoteTags(ctx, bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}, limit)
+}
+
+func (r *Resolver) EmotesByTag(ctx context.Context, tag string, pageArg *int, limitArg *int) (*model.EmoteSearchResult, error) {
+	limit := 20
+	if limitArg != nil && *limitArg > 0 {
+		limit = *limitArg
+	}
+	if limit > EMOTES_QUERY_LIMIT {
+		limit = EMOTES_QUERY_LIMIT
+	}
+	page := 1
+	if pageArg != nil && *pageArg > 1 {
+		page = *pageArg
+	}
+
+	match := bson.M{
+		"versions.0.state.lifecycle": structures.EmoteLifecycleLive,
+		"tags":                       strings.ToLower(tag),
+	}
+	if !r.includeHiddenEmotes(ctx, nil) {
+		for _, e := range listedEmotesFilter {
+			match[e.Key] = e.Value
+		}
+	}
+
+	count, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).CountDocuments(ctx, match)
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to count emotes by tag")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	emotes := []*structures.Emote{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, aggregations.Combine(
+		mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$addFields", Value: bson.M{"channel_count": bson.M{"$sum": "$versions.state.channel_count"}}}},
+			{{Key: "$sort", Value: bson.D{{Key: "channel_count", Value: -1}, {Key: "_id", Value: -1}}}},
+			{{Key: "$skip", Value: (page - 1) * limit}},
+			{{Key: "$limit", Value: limit}},
+		},
+		aggregations.GetEmoteRelationshipOwner(aggregations.UserRelationshipOptions{Roles: true}),
+	))
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch emotes by tag")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	models := make([]*model.Emote, len(emotes))
+	for i, e := range emotes {
+		// Bring forward the default version
+		if len(e.Versions) > 0 {
+			e.ID = e.Versions[0].ID
+		}
+		models[i] = helpers.EmoteStructureToModel(r.Ctx, e)
+	}
+
+	return &model.EmoteSearchResult{
+		Count: int(count),
+		Items: models,
+	}, nil
+}
+
+func (r *Resolver) BannedEmoteTags(ctx context.Context) ([]*model.BannedEmoteTag, error) {
+	bans := []*helpers.EmoteTagBan{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(configure.CollectionNameEmoteTagBans).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err == nil {
+		err = cur.All(ctx, &bans)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch banned emote tags")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	result := make([]*model.BannedEmoteTag, len(bans))
+	for i, b := range bans {
+		result[i] = helpers.EmoteTagBanToModel(b)
+	}
+	return result, nil
+}
+
+// popularEmoteTags returns the most used tags, which are cached for a while
+func (r *Resolver) popularEmoteTags(ctx context.Context) ([]*model.EmoteTag, error) {
+	k := r.Ctx.Inst().Redis.ComposeKey("gql-v3", "emote-tags:popular")
+
+	tags := []*model.EmoteTag{}
+	if v, err := r.Ctx.Inst().Redis.Get(ctx, k); err == nil && v != "" {
+		if err = json.Unmarshal(utils.S2B(v), &tags); err == nil {
+			return tags, nil
+		}
+		logrus.WithError(err).Error("couldn't decode popular emote tags")
+	}
+
+	tags, err := r.aggregateEmoteTags(ctx, nil, EMOTE_TAGS_POPULAR_COUNT)
+	if err != nil {
+		return nil, err
+	}
+
+	b, _ := json.Marshal(tags)
+	if err = r.Ctx.Inst().Redis.SetEX(ctx, k, utils.B2S(b), EMOTE_TAGS_POPULAR_TTL); err != nil {
+		logrus.WithError(err).Error("redis, failed to cache popular emote tags")
+	}
+	return tags, nil
+}
+
+// aggregateEmoteTags counts the uses of tags across live emotes, optionally only those matching a condition
+func (r *Resolver) aggregateEmoteTags(ctx context.Context, cond interface{}, limit int) ([]*model.EmoteTag, error) {
+	match := bson.M{"versions.0.state.lifecycle": structures.EmoteLifecycleLive}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	if cond != nil {
+		match["tags"] = cond
+		pipeline = append(pipeline, bson.D{{Key: "$unwind", Value: "$tags"}}, bson.D{{Key: "$match", Value: bson.M{"tags": cond}}})
+	} else {
+		pipeline = append(pipeline, bson.D{{Key: "$unwind", Value: "$tags"}})
+	}
+	pipeline = append(pipeline, []bson.D{
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}...)
+
+	rows := []struct {
+		Name  string `bson:"_id"`
+		Count int    `bson:"count"`
+	}{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, pipeline)
+	if err == nil {
+		err = cur.All(ctx, &rows)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to aggregate emote tags")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	result := make([]*model.EmoteTag, len(rows))
+	for i, row := range rows {
+		result[i] = &model.EmoteTag{Name: row.Name, Count: row.Count}
+	}
+	return result, nil
+}
@@ -3,11 +3,20 @@ package role
 import (
 	"context"
 
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/aggregations"
 	"github.com/SevenTV/GQL/graph/generated"
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/types"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
+const ROLE_MEMBERS_QUERY_LIMIT = 100
+
 type Resolver struct {
 	types.Resolver
 }
@@ -16,7 +25,90 @@ func New(r types.Resolver) generated.RoleResolver {
 	return &Resolver{r}
 }
 
-func (r *Resolver) Members(ctx context.Context, obj *model.Role, page *int, limit *int) ([]*model.User, error) {
-	// TODO
-	return nil, nil
+func (r *Resolver) Members(ctx context.Context, obj *model.Role, pageArg *int, limitArg *int) ([]*model.User, error) {
+	limit := ROLE_MEMBERS_QUERY_LIMIT
+	if limitArg != nil && *limitArg > 0 && *limitArg < limit {
+		limit = *limitArg
+	}
+	page := 1
+	if pageArg != nil && *pageArg > 1 {
+		page = *pageArg
+	}
+
+	users := []*structures.User{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Aggregate(ctx, aggregations.Combine(
+		mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"role_ids": obj.ID}}},
+			{{Key: "$sort", Value: bson.M{"_id": 1}}},
+			{{Key: "$skip", Value: (page - 1) * limit}},
+			{{Key: "$limit", Value: limit}},
+		},
+		aggregations.UserRelationRoles,
+	))
+	if err == nil {
+		err = cur.All(ctx, &users)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch role members")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	result := make([]*model.User, len(users))
+	for i, u := range users {
+		result[i] = helpers.UserStructureToModel(r.Ctx, u)
+	}
+	return result, nil
+}
+
+func (r *Resolver) MembersConnection(ctx context.Context, obj *model.Role, firstArg *int, after *string) (*model.UserListConnection, error) {
+	first := ROLE_MEMBERS_QUERY_LIMIT
+	if firstArg != nil && *firstArg > 0 && *firstArg < first {
+		first = *firstArg
+	}
+
+	page, err := helpers.CursorPipeline(after, first, 1)
+	if err != nil {
+		return nil, errors.ErrInvalidRequest().SetDetail(err.Error())
+	}
+
+	items := []bson.Raw{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameUsers).Aggregate(ctx, aggregations.Combine(
+		mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"role_ids": obj.ID}}},
+			{{Key: "$addFields", Value: bson.M{helpers.CursorSortKey: "$_id"}}},
+		},
+		page,
+		aggregations.UserRelationRoles,
+	))
+	if err == nil {
+		err = cur.All(ctx, &items)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to fetch role members")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	items, cursors, info, err := helpers.CursorEdges(items, first, after)
+	if err != nil {
+		logrus.WithError(err).Error("failed to encode cursors")
+		return nil, errors.ErrInternalServerError()
+	}
+	edges := make([]*model.UserListEdge, 0, len(items))
+	for i, item := range items {
+		u := &structures.User{}
+		if err = bson.Unmarshal(item, u); err != nil {
+			logrus.WithError(err).Error("mongo, failed to decode user")
+			continue
+		}
+
+		edges = append(edges, &model.UserListEdge{
+			Cursor: cursors[i],
+			Node:   helpers.UserStructureToModel(r.Ctx, u),
+		})
+	}
+
+	return &model.UserListConnection{
+		Edges:    edges,
+		PageInfo: info,
+	}, nil
 }
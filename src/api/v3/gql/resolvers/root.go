@@ -44,6 +44,14 @@ func (r *Resolver) EmoteVersion() generated.EmoteVersionResolver {
 	return emote.NewVersion(r.Resolver)
 }
 
+func (r *Resolver) EmoteComment() generated.EmoteCommentResolver {
+	return emote.NewComment(r.Resolver)
+}
+
+func (r *Resolver) DeletedEmote() generated.DeletedEmoteResolver {
+	return emote.NewDeleted(r.Resolver)
+}
+
 func (r *Resolver) Mutation() generated.MutationResolver {
 	return mutation.New(r.Resolver)
 }
@@ -0,0 +1,65 @@
+package subscription
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/errors"
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
+	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *Resolver) EmoteComments(ctx context.Context, emoteID primitive.ObjectID) (<-chan *model.EmoteComment, error) {
+	// Comments are published under the emote's ID, which may differ from the version requested
+	emote := &structures.Emote{}
+	if err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{
+		"$or": bson.A{bson.M{"_id": emoteID}, bson.M{"versions.id": emoteID}},
+	}, options.FindOne().SetProjection(bson.M{"flags": 1, "owner_id": 1})).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrUnknownEmote()
+		}
+		logrus.WithError(err).Error("mongo, failed to fetch emote")
+		return nil, errors.ErrInternalServerError()
+	}
+	if emote.Flags&structures.EmoteFlagsPrivate != 0 && !auth.CanSeePrivateEmotesOf(ctx, emote.OwnerID) {
+		return nil, errors.ErrUnknownEmote()
+	}
+
+	ch := make(chan *model.EmoteComment, 1)
+	go func() {
+		defer close(ch)
+		sub := r.subscribe(ctx, "emote_comments", emote.ID)
+		for msg := range sub {
+			id, err := primitive.ObjectIDFromHex(msg)
+			if err != nil {
+				continue
+			}
+
+			comment := &structures.Message{}
+			if err = r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameMessages).FindOne(ctx, bson.M{"_id": id}).Decode(comment); err != nil {
+				if err != mongo.ErrNoDocuments {
+					logrus.WithError(err).Error("mongo, failed to fetch emote comment")
+					continue
+				}
+
+				// The comment was deleted
+				ch <- &model.EmoteComment{
+					ID:      id,
+					EmoteID: emote.ID,
+					Author:  &model.User{},
+					Deleted: true,
+				}
+				continue
+			}
+			ch <- helpers.EmoteCommentStructureToModel(r.Ctx, comment)
+		}
+	}()
+
+	return ch, nil
+}
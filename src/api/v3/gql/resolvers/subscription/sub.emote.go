@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
 	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,7 +13,7 @@ import (
 func (r *Resolver) Emote(ctx context.Context, id primitive.ObjectID, init *bool) (<-chan *model.EmotePartial, error) {
 	getEmote := func() *model.EmotePartial {
 		emote, err := loaders.For(ctx).EmoteByID.Load(id)
-		if err != nil {
+		if err != nil || !auth.CanSeeEmote(ctx, emote) {
 			return nil
 		}
 		return helpers.EmoteStructureToPartialModel(r.Ctx, emote)
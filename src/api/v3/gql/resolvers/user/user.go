@@ -7,8 +7,10 @@ import (
 	"github.com/SevenTV/Common/errors"
 	"github.com/SevenTV/Common/mongo"
 	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/aggregations"
 	"github.com/SevenTV/GQL/graph/generated"
 	"github.com/SevenTV/GQL/graph/model"
+	"github.com/SevenTV/GQL/src/api/v3/gql/auth"
 	"github.com/SevenTV/GQL/src/api/v3/gql/helpers"
 	"github.com/SevenTV/GQL/src/api/v3/gql/loaders"
 	"github.com/SevenTV/GQL/src/api/v3/gql/types"
@@ -18,6 +20,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const USER_OWNED_EMOTES_QUERY_LIMIT = 100
+
 type Resolver struct {
 	types.Resolver
 }
@@ -126,9 +130,7 @@ func (r *Resolver) EditorOf(ctx context.Context, obj *model.User) ([]*model.User
 func (r *Resolver) OwnedEmotes(ctx context.Context, obj *model.User) ([]*model.Emote, error) {
 	emotes := []*structures.Emote{}
 	errs := []error{}
-	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{
-		"owner_id": obj.ID,
-	})
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, ownedEmotesMatch(ctx, obj))
 	if err == nil {
 		if err = cur.All(ctx, &emotes); err != nil {
 			logrus.WithError(err).Error("mongo, failed to retrieve user's owned emotes")
@@ -142,6 +144,73 @@ func (r *Resolver) OwnedEmotes(ctx context.Context, obj *model.User) ([]*model.E
 	return result, multierror.Append(nil, errs...).ErrorOrNil()
 }
 
+func (r *Resolver) OwnedEmotesConnection(ctx context.Context, obj *model.User, firstArg *int, after *string) (*model.EmoteConnection, error) {
+	first := USER_OWNED_EMOTES_QUERY_LIMIT
+	if firstArg != nil && *firstArg > 0 && *firstArg < first {
+		first = *firstArg
+	}
+
+	// Newest emotes first
+	page, err := helpers.CursorPipeline(after, first, -1)
+	if err != nil {
+		return nil, errors.ErrInvalidRequest().SetDetail(err.Error())
+	}
+
+	items := []bson.Raw{}
+	cur, err := r.Ctx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, aggregations.Combine(
+		mongo.Pipeline{
+			{{Key: "$match", Value: ownedEmotesMatch(ctx, obj)}},
+			{{Key: "$addFields", Value: bson.M{helpers.CursorSortKey: "$_id"}}},
+		},
+		page,
+	))
+	if err == nil {
+		err = cur.All(ctx, &items)
+	}
+	if err != nil {
+		logrus.WithError(err).Error("mongo, failed to retrieve user's owned emotes")
+		return nil, errors.ErrInternalServerError()
+	}
+
+	items, cursors, info, err := helpers.CursorEdges(items, first, after)
+	if err != nil {
+		logrus.WithError(err).Error("failed to encode cursors")
+		return nil, errors.ErrInternalServerError()
+	}
+	edges := make([]*model.EmoteEdge, 0, len(items))
+	for i, item := range items {
+		e := &structures.Emote{}
+		if err = bson.Unmarshal(item, e); err != nil {
+			logrus.WithError(err).Error("mongo, failed to decode emote")
+			continue
+		}
+
+		// Bring forward the default version
+		if len(e.Versions) > 0 {
+			e.ID = e.Versions[0].ID
+		}
+		edges = append(edges, &model.EmoteEdge{
+			Cursor: cursors[i],
+			Node:   helpers.EmoteStructureToModel(r.Ctx, e),
+		})
+	}
+
+	return &model.EmoteConnection{
+		Edges:    edges,
+		PageInfo: info,
+	}, nil
+}
+
+// ownedEmotesMatch matches the emotes of a user, leaving out private ones unless the actor may see them
+func ownedEmotesMatch(ctx context.Context, obj *model.User) bson.M {
+	match := bson.M{"owner_id": obj.ID}
+	if !auth.CanSeePrivateEmotesOf(ctx, obj.ID) {
+		match["flags"] = bson.M{"$bitsAllClear": structures.EmoteFlagsPrivate}
+	}
+
+	return match
+}
+
 func (r *Resolver) InboxUnreadCount(ctx context.Context, obj *model.User) (int, error) {
 	// TODO
 	return 0, nil
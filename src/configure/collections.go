@@ -7,6 +7,16 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Collections owned by this service
+const (
+	CollectionNameEmoteTransfers  mongo.CollectionName = "emote_transfers"
+	CollectionNameEmoteDeletions  mongo.CollectionName = "emote_deletions"
+	CollectionNameEmoteTagBans    mongo.CollectionName = "emote_tag_bans"
+	CollectionNameEmoteDecisions  mongo.CollectionName = "emote_moderation_decisions"
+	CollectionNameEmoteStats      mongo.CollectionName = "emote_stats"
+	CollectionNameEmoteSetHistory mongo.CollectionName = "emote_set_history"
+)
+
 var Indexes = []mongo.IndexRef{
 	{
 		Collection: mongo.CollectionNameEmoteSets,
@@ -23,4 +33,72 @@ var Indexes = []mongo.IndexRef{
 			Keys: bson.M{"emote_ids": 1},
 		},
 	},
+	{
+		Collection: mongo.CollectionNameEmotes,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"tags": 1},
+		},
+	},
+	{
+		Collection: mongo.CollectionNameEmotes,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"versions.phash_bands": 1},
+		},
+	},
+	{
+		Collection: mongo.CollectionNameMessages,
+		Index: mongo.IndexModel{
+			Keys: bson.D{{Key: "kind", Value: 1}, {Key: "data.emote_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+	},
+	{
+		Collection: CollectionNameEmoteTransfers,
+		Index: mongo.IndexModel{
+			Keys: bson.D{{Key: "emote_id", Value: 1}, {Key: "status", Value: 1}},
+		},
+	},
+	{
+		Collection: CollectionNameEmoteSetHistory,
+		Index: mongo.IndexModel{
+			Keys: bson.D{{Key: "emote_set_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+	},
+	{
+		Collection: CollectionNameEmoteDecisions,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"emote_id": 1},
+		},
+	},
+	{
+		// Deletion records lapse once their retention period is over
+		Collection: CollectionNameEmoteDeletions,
+		Index: mongo.IndexModel{
+			Keys:    bson.M{"expires_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	{
+		// Restorable deletions are listed from the most recent
+		Collection: CollectionNameEmoteDeletions,
+		Index: mongo.IndexModel{
+			Keys: bson.M{"deleted_at": -1},
+		},
+	},
+	{
+		Collection: CollectionNameEmoteStats,
+		Index: mongo.IndexModel{
+			Keys: bson.D{{Key: "emote_id", Value: 1}, {Key: "date", Value: 1}},
+			Options: &options.IndexOptions{
+				Unique: utils.BoolPointer(true),
+			},
+		},
+	},
+	{
+		// Stats are kept for a little over a year
+		Collection: CollectionNameEmoteStats,
+		Index: mongo.IndexModel{
+			Keys:    bson.M{"date": 1},
+			Options: options.Index().SetExpireAfterSeconds(400 * 24 * 60 * 60),
+		},
+	},
 }
@@ -84,12 +84,21 @@ func BindEnvs(config *viper.Viper, iface interface{}, parts ...string) {
 
 type Config struct {
 	Level      string `mapstructure:"level" json:"level"`
+	Env        string `mapstructure:"env" json:"env"`
 	ConfigFile string `mapstructure:"config" json:"config"`
 	NoHeader   bool   `mapstructure:"noheader" json:"noheader"`
 	WebsiteURL string `mapstructure:"website_url" json:"website_url"`
 	NodeName   string `mapstructure:"node_name" json:"node_name"`
 	CdnURL     string `mapstructure:"cdn_url" json:"cdn_url"`
 
+	Cdn struct {
+		// The scheme of CDN URLs, i.e "https". URLs are protocol-relative when empty
+		Scheme string `mapstructure:"scheme" json:"scheme"`
+		// When set, the URLs to files of private emotes are signed with this secret and expire
+		SigningSecret    string `mapstructure:"signing_secret" json:"signing_secret"`
+		SignedURLMinutes int    `mapstructure:"signed_url_minutes" json:"signed_url_minutes"`
+	} `mapstructure:"cdn" json:"cdn"`
+
 	Redis struct {
 		URI      string `mapstructure:"uri" json:"uri"`
 		Username string `mapstructure:"username" json:"username"`
@@ -119,7 +128,73 @@ type Config struct {
 		} `mapstructure:"platforms" json:"platforms"`
 	} `mapstructure:"auth" json:"auth"`
 
+	Storage struct {
+		Type string `mapstructure:"type" json:"type"`
+		Path string `mapstructure:"path" json:"path"`
+	} `mapstructure:"storage" json:"storage"`
+
+	Upload struct {
+		MaxSize   int64 `mapstructure:"max_size" json:"max_size"`
+		MaxWidth  int   `mapstructure:"max_width" json:"max_width"`
+		MaxHeight int   `mapstructure:"max_height" json:"max_height"`
+	} `mapstructure:"upload" json:"upload"`
+
+	Emotes struct {
+		// How long a deleted emote can be restored for
+		DeletionRetentionDays int `mapstructure:"deletion_retention_days" json:"deletion_retention_days"`
+		// What to do with uploads resembling a deleted or rejected emote once processed: "off", "warn" or "block"
+		DuplicateAction string `mapstructure:"duplicate_action" json:"duplicate_action"`
+		// Defaults to the similarity threshold of perceptual hashes, and cannot exceed 7 bits
+		DuplicateThreshold int `mapstructure:"duplicate_threshold" json:"duplicate_threshold"`
+		// How many comments a user may post per minute
+		CommentsPerMinute int `mapstructure:"comments_per_minute" json:"comments_per_minute"`
+	} `mapstructure:"emotes" json:"emotes"`
+
+	EmoteSets struct {
+		// The number of slots a set can have
+		MaxSlots int `mapstructure:"max_slots" json:"max_slots"`
+		// Roles allowing the sets of their members to have more slots, by role ID
+		RoleMaxSlots map[string]int `mapstructure:"role_max_slots" json:"role_max_slots"`
+	} `mapstructure:"emote_sets" json:"emote_sets"`
+
+	Jobs struct {
+		Type       string `mapstructure:"type" json:"type"`
+		Workers    int    `mapstructure:"workers" json:"workers"`
+		FFmpegPath string `mapstructure:"ffmpeg_path" json:"ffmpeg_path"`
+	} `mapstructure:"jobs" json:"jobs"`
+
+	Trending struct {
+		// The span of set additions considered when scoring emotes
+		WindowHours int `mapstructure:"window_hours" json:"window_hours"`
+		// How often the scores are recomputed
+		RefreshSeconds int `mapstructure:"refresh_seconds" json:"refresh_seconds"`
+	} `mapstructure:"trending" json:"trending"`
+
+	Search struct {
+		Type string `mapstructure:"type" json:"type"`
+		// How often the index is rebuilt from scratch, refreshing channel counts
+		RebuildMinutes int `mapstructure:"rebuild_minutes" json:"rebuild_minutes"`
+	} `mapstructure:"search" json:"search"`
+
+	Stats struct {
+		// How often the channel counts of emotes are recorded
+		SnapshotMinutes int `mapstructure:"snapshot_minutes" json:"snapshot_minutes"`
+	} `mapstructure:"stats" json:"stats"`
+
+	Rest struct {
+		Routes []struct {
+			Method   string `mapstructure:"method" json:"method"`
+			Path     string `mapstructure:"path" json:"path"`
+			Document string `mapstructure:"document" json:"document"`
+		} `mapstructure:"routes" json:"routes"`
+	} `mapstructure:"rest" json:"rest"`
+
 	Credentials struct {
 		JWTSecret string `mapstructure:"jwt_secret" json:"jwt_secret"`
 	} `mapstructure:"credentials" json:"credentials"`
 }
+
+// IsDevelopment returns whether or not the app is running in a development environment
+func (c *Config) IsDevelopment() bool {
+	return c.Env == "dev" || c.Env == "development"
+}
@@ -0,0 +1,99 @@
+package emotes
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/Common/structures/v3/mutations"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReviewState is the moderation state of an emote version, stored beside its state as the emote structure has no room for it
+type ReviewState string
+
+const (
+	// New versions await review and are unlisted until approved
+	ReviewStatePending  ReviewState = "PENDING"
+	ReviewStateApproved ReviewState = "APPROVED"
+	ReviewStateRejected ReviewState = "REJECTED"
+)
+
+// Decision is the record of a version being approved or rejected
+type Decision struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	EmoteID   primitive.ObjectID `bson:"emote_id"`
+	VersionID primitive.ObjectID `bson:"version_id"`
+	// Decisions made automatically, such as the rejection of duplicates, have no actor
+	ActorID  primitive.ObjectID `bson:"actor_id"`
+	Approved bool               `bson:"approved"`
+	Reason   string             `bson:"reason,omitempty"`
+}
+
+// Decide records a decision on a version, whose review state must already be written, and lets the owner of the emote know
+func Decide(ctx context.Context, gCtx global.Context, actor *structures.User, emote *structures.Emote, ver *structures.EmoteVersion, approve bool, reason string) {
+	decision := &Decision{
+		ID:        primitive.NewObjectID(),
+		EmoteID:   emote.ID,
+		VersionID: ver.ID,
+		Approved:  approve,
+		Reason:    reason,
+	}
+	if actor != nil {
+		decision.ActorID = actor.ID
+	}
+	if _, err := gCtx.Inst().Mongo.Collection(configure.CollectionNameEmoteDecisions).InsertOne(ctx, decision); err != nil {
+		logrus.WithError(err).Error("mongo, failed to record emote moderation decision")
+	}
+
+	subject := "inbox.generic.emote_approved"
+	if !approve {
+		subject = "inbox.generic.emote_rejected"
+	}
+	NotifyOwner(ctx, gCtx, actor, emote, ver, subject, !approve, map[string]string{
+		"REASON": reason,
+	})
+}
+
+// NotifyOwner sends a message about a version to the owner of the emote.
+// The emote and version are given as placeholders, in addition to those specified.
+// Messages without an actor are sent by the system
+func NotifyOwner(ctx context.Context, gCtx global.Context, actor *structures.User, emote *structures.Emote, ver *structures.EmoteVersion, subject string, important bool, placeholders map[string]string) {
+	authorID := gCtx.Inst().Mongo.System(ctx).AdminUserID
+	if actor != nil {
+		authorID = actor.ID
+	}
+
+	p := map[string]string{
+		"EMOTE_ID":     emote.ID.Hex(),
+		"EMOTE_NAME":   emote.Name,
+		"VERSION_ID":   ver.ID.Hex(),
+		"VERSION_NAME": ver.Name,
+	}
+	for k, v := range placeholders {
+		p[k] = v
+	}
+
+	mb := structures.NewMessageBuilder(&structures.Message{}).
+		SetKind(structures.MessageKindInbox).
+		SetAuthorID(authorID).
+		SetTimestamp(time.Now()).
+		AsInbox(structures.MessageDataInbox{
+			Subject:      subject + ".subject",
+			Content:      subject + ".content",
+			Important:    important,
+			Placeholders: p,
+		})
+	mm := mutations.MessageMutation{
+		MessageBuilder: mb,
+	}
+	if _, err := mm.SendInboxMessage(ctx, gCtx.Inst().Mongo, mutations.SendInboxMessageOptions{
+		Actor:      actor,
+		Recipients: []primitive.ObjectID{emote.OwnerID},
+	}); err != nil {
+		logrus.WithError(err).Error("mutation, failed to send emote moderation message")
+	}
+}
@@ -0,0 +1,104 @@
+package emotes
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/images"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// How many emotes sharing enough bands with the searched hashes are compared, those sharing the most bands first
+const SIMILAR_CANDIDATE_LIMIT = 1000
+
+// Hashes is the perceptual hash data stored on the versions of an emote, which the emote structure doesn't include
+type Hashes struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Versions []struct {
+		ID     primitive.ObjectID           `bson:"id"`
+		State  structures.EmoteVersionState `bson:"state"`
+		Review ReviewState                  `bson:"review,omitempty"`
+		Hashes []int64                      `bson:"phash"`
+	} `bson:"versions"`
+}
+
+// SimilarVersion is a version of an emote whose images resemble those searched for
+type SimilarVersion struct {
+	EmoteID   primitive.ObjectID
+	VersionID primitive.ObjectID
+	State     structures.EmoteVersionState
+	Review    ReviewState
+	Distance  int
+}
+
+// FindSimilarVersions returns the versions of other emotes within threshold bits of the hashes, most similar first.
+// The threshold is capped to images.PHASH_MAX_THRESHOLD, above which similar versions could not be found by their bands
+func FindSimilarVersions(ctx context.Context, gCtx global.Context, hashes []int64, excludeEmoteID primitive.ObjectID, threshold int) ([]SimilarVersion, error) {
+	result := []SimilarVersion{}
+	if len(hashes) == 0 {
+		return result, nil
+	}
+	if threshold > images.PHASH_MAX_THRESHOLD {
+		threshold = images.PHASH_MAX_THRESHOLD
+	}
+
+	// Most images share a band or two by chance, but those within the threshold must share several.
+	// Candidates are ranked by how many bands they share with the hashes, so that the limit drops the least alike first
+	bands := images.PerceptualHashBands(hashes)
+	emotes := []*Hashes{}
+	cur, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"_id":                  bson.M{"$ne": excludeEmoteID},
+			"versions.phash_bands": bson.M{"$in": bands},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"versions.id":     1,
+			"versions.state":  1,
+			"versions.review": 1,
+			"versions.phash":  1,
+			"shared_bands": bson.M{"$size": bson.M{"$setIntersection": bson.A{
+				bson.M{"$reduce": bson.M{
+					"input":        "$versions.phash_bands",
+					"initialValue": bson.A{},
+					"in":           bson.M{"$concatArrays": bson.A{"$$value", bson.M{"$ifNull": bson.A{"$$this", bson.A{}}}}},
+				}},
+				bands,
+			}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"shared_bands": bson.M{"$gte": images.PerceptualHashMinSharedBands(threshold)}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "shared_bands", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: SIMILAR_CANDIDATE_LIMIT}},
+	})
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range emotes {
+		for _, ver := range e.Versions {
+			if len(ver.Hashes) == 0 {
+				continue
+			}
+			if d := images.PerceptualHashDistance(hashes, ver.Hashes); d <= threshold {
+				result = append(result, SimilarVersion{
+					EmoteID:   e.ID,
+					VersionID: ver.ID,
+					State:     ver.State,
+					Review:    ver.Review,
+					Distance:  d,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Distance < result[j].Distance
+	})
+	return result, nil
+}
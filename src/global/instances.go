@@ -4,10 +4,17 @@ import (
 	"github.com/SevenTV/Common/mongo"
 	"github.com/SevenTV/Common/redis"
 	"github.com/SevenTV/Common/structures/v3/query"
+	"github.com/SevenTV/GQL/src/instance"
 )
 
 type Instances struct {
 	Mongo mongo.Instance
 	Redis redis.Instance
 	Query *query.Query
+
+	Storage  instance.Storage
+	Jobs     instance.Jobs
+	Trending instance.Trending
+	Search   instance.SearchIndex
+	Stats    instance.Stats
 }
@@ -0,0 +1,251 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/gif"
+	"image/png"
+
+	"github.com/SevenTV/Common/structures/v3"
+)
+
+// Metadata describes an image, as read from its header
+type Metadata struct {
+	Format     structures.EmoteFormatName
+	Width      int
+	Height     int
+	FrameCount int
+}
+
+// Animated returns whether or not the image has more than one frame
+func (m Metadata) Animated() bool {
+	return m.FrameCount > 1
+}
+
+// MAX_FRAME_COUNT is the number of frames past which an image is refused
+const MAX_FRAME_COUNT = 1000
+
+// DecodeMetadata: Identify the format and dimensions of an image
+//
+// Supported formats are PNG (incl. APNG), GIF, WEBP and AVIF
+func DecodeMetadata(b []byte) (Metadata, error) {
+	var (
+		m   Metadata
+		err error
+	)
+	switch {
+	case bytes.HasPrefix(b, pngSignature):
+		m, err = decodePNGMetadata(b)
+	case bytes.HasPrefix(b, []byte("GIF87a")), bytes.HasPrefix(b, []byte("GIF89a")):
+		m, err = decodeGIFMetadata(b)
+	case len(b) >= 12 && bytes.Equal(b[0:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("WEBP")):
+		m, err = decodeWEBPMetadata(b)
+	case len(b) >= 12 && bytes.Equal(b[4:8], []byte("ftyp")):
+		m, err = decodeAVIFMetadata(b)
+	default:
+		return m, fmt.Errorf("unsupported image format")
+	}
+	if err != nil {
+		return m, err
+	}
+
+	if m.Width <= 0 || m.Height <= 0 {
+		return m, fmt.Errorf("invalid image dimensions")
+	}
+	if m.FrameCount < 1 || m.FrameCount > MAX_FRAME_COUNT {
+		return m, fmt.Errorf("invalid frame count (max %d)", MAX_FRAME_COUNT)
+	}
+	return m, nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func decodePNGMetadata(b []byte) (Metadata, error) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	// APNG stores its frame count in the acTL chunk, which comes before the image data
+	frames := 1
+	for pos := len(pngSignature); pos+8 <= len(b); {
+		size := int(binary.BigEndian.Uint32(b[pos : pos+4]))
+		id := string(b[pos+4 : pos+8])
+		if id == "IDAT" || size < 0 || pos+12+size > len(b) {
+			break
+		}
+		if id == "acTL" && size >= 8 {
+			frames = int(binary.BigEndian.Uint32(b[pos+8 : pos+12]))
+			break
+		}
+
+		// Length, type and CRC surround the data
+		pos += 12 + size
+	}
+
+	return Metadata{
+		Format:     structures.EmoteFormatNamePNG,
+		Width:      cfg.Width,
+		Height:     cfg.Height,
+		FrameCount: frames,
+	}, nil
+}
+
+func decodeGIFMetadata(b []byte) (Metadata, error) {
+	cfg, err := gif.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	frames, err := countGIFFrames(b)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	return Metadata{
+		Format:     structures.EmoteFormatNameGIF,
+		Width:      cfg.Width,
+		Height:     cfg.Height,
+		FrameCount: frames,
+	}, nil
+}
+
+// countGIFFrames walks the blocks of a GIF, counting image descriptors without decoding their data
+func countGIFFrames(b []byte) (int, error) {
+	errTruncated := fmt.Errorf("truncated gif")
+	if len(b) < 13 {
+		return 0, errTruncated
+	}
+
+	// Header and logical screen descriptor, followed by the global color table
+	pos := 13
+	if flags := b[10]; flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1)
+	}
+
+	// skipSubBlocks moves past a sequence of data sub-blocks, ended by an empty one
+	skipSubBlocks := func() error {
+		for {
+			if pos >= len(b) {
+				return errTruncated
+			}
+			size := int(b[pos])
+			pos += 1 + size
+			if size == 0 {
+				return nil
+			}
+		}
+	}
+
+	frames := 0
+	for pos < len(b) {
+		switch b[pos] {
+		case 0x21: // extension: label, then sub-blocks
+			pos += 2
+			if err := skipSubBlocks(); err != nil {
+				return frames, err
+			}
+		case 0x2c: // image descriptor, then an optional local color table, the LZW code size and sub-blocks
+			if pos+10 > len(b) {
+				return frames, errTruncated
+			}
+			flags := b[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1)
+			}
+			pos++
+			if err := skipSubBlocks(); err != nil {
+				return frames, err
+			}
+
+			frames++
+			if frames > MAX_FRAME_COUNT {
+				return frames, fmt.Errorf("too many frames (max %d)", MAX_FRAME_COUNT)
+			}
+		case 0x3b: // trailer
+			return frames, nil
+		default:
+			return frames, fmt.Errorf("bad gif block")
+		}
+	}
+
+	// Some encoders omit the trailer
+	if frames == 0 {
+		return 0, errTruncated
+	}
+	return frames, nil
+}
+
+func decodeWEBPMetadata(b []byte) (Metadata, error) {
+	m := Metadata{Format: structures.EmoteFormatNameWEBP, FrameCount: 1}
+
+	// Iterate over RIFF chunks
+	frames := 0
+	for pos := 12; pos+8 <= len(b); {
+		id := string(b[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(b[pos+4 : pos+8]))
+		data := b[pos+8:]
+		if size > len(data) {
+			return m, fmt.Errorf("truncated webp chunk")
+		}
+		data = data[:size]
+
+		switch id {
+		case "VP8X": // extended format: 24-bit canvas size, minus one
+			if len(data) < 10 {
+				return m, fmt.Errorf("bad VP8X chunk")
+			}
+			m.Width = int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16) + 1
+			m.Height = int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16) + 1
+		case "VP8 ": // lossy
+			if m.Width == 0 && len(data) >= 10 {
+				m.Width = int(binary.LittleEndian.Uint16(data[6:8]) & 0x3fff)
+				m.Height = int(binary.LittleEndian.Uint16(data[8:10]) & 0x3fff)
+			}
+		case "VP8L": // lossless: 14-bit dimensions, minus one
+			if m.Width == 0 && len(data) >= 5 {
+				bits := binary.LittleEndian.Uint32(data[1:5])
+				m.Width = int(bits&0x3fff) + 1
+				m.Height = int((bits>>14)&0x3fff) + 1
+			}
+		case "ANMF":
+			frames++
+		}
+
+		// Chunks are padded to an even size
+		pos += 8 + size + size%2
+	}
+	if frames > 0 {
+		m.FrameCount = frames
+	}
+
+	if m.Width == 0 || m.Height == 0 {
+		return m, fmt.Errorf("could not read webp dimensions")
+	}
+	return m, nil
+}
+
+func decodeAVIFMetadata(b []byte) (Metadata, error) {
+	m := Metadata{Format: structures.EmoteFormatNameAVIF, FrameCount: 1}
+
+	brand := string(b[8:12])
+	switch brand {
+	case "avif":
+	case "avis": // image sequence, the exact frame count is determined during processing
+		m.FrameCount = 2
+	default:
+		return m, fmt.Errorf("unsupported image format")
+	}
+
+	// The image spatial extents property holds the dimensions
+	i := bytes.Index(b, []byte("ispe"))
+	if i < 0 || len(b) < i+16 {
+		return m, fmt.Errorf("could not read avif dimensions")
+	}
+	m.Width = int(binary.BigEndian.Uint32(b[i+8 : i+12]))
+	m.Height = int(binary.BigEndian.Uint32(b[i+12 : i+16]))
+
+	return m, nil
+}
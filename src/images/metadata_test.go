@@ -0,0 +1,203 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/SevenTV/Common/structures/v3"
+)
+
+func TestDecodeMetadata(t *testing.T) {
+	encodePNG := func(w, h int) []byte {
+		buf := bytes.Buffer{}
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	// apng inserts an animation control chunk after the header of a PNG
+	apng := func(w, h int, frames uint32) []byte {
+		b := encodePNG(w, h)
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint32(data[0:4], frames)
+
+		chunk := make([]byte, 0, 20)
+		chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(data)))
+		chunk = append(chunk, "acTL"...)
+		chunk = append(chunk, data...)
+		chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+		ihdrEnd := len(pngSignature) + 12 + 13
+		return append(append(append([]byte{}, b[:ihdrEnd]...), chunk...), b[ihdrEnd:]...)
+	}
+	encodeGIF := func(w, h, frames int) []byte {
+		g := &gif.GIF{}
+		for i := 0; i < frames; i++ {
+			im := image.NewPaletted(image.Rect(0, 0, w, h), palette.Plan9)
+			im.Set(0, 0, color.White)
+			g.Image = append(g.Image, im)
+			g.Delay = append(g.Delay, 10)
+		}
+
+		buf := bytes.Buffer{}
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	// riff wraps chunks in a WEBP container
+	riff := func(chunks ...[]byte) []byte {
+		body := []byte("WEBP")
+		for _, c := range chunks {
+			body = append(body, c...)
+		}
+		b := append([]byte("RIFF"), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(b[4:], uint32(len(body)))
+		return append(b, body...)
+	}
+	chunk := func(id string, data []byte) []byte {
+		c := append([]byte(id), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(c[4:], uint32(len(data)))
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	vp8x := func(w, h int) []byte {
+		d := make([]byte, 10)
+		d[4], d[5], d[6] = byte(w-1), byte((w-1)>>8), byte((w-1)>>16)
+		d[7], d[8], d[9] = byte(h-1), byte((h-1)>>8), byte((h-1)>>16)
+		return chunk("VP8X", d)
+	}
+	vp8l := func(w, h int) []byte {
+		d := make([]byte, 5)
+		d[0] = 0x2f
+		binary.LittleEndian.PutUint32(d[1:], uint32(w-1)|uint32(h-1)<<14)
+		return chunk("VP8L", d)
+	}
+	avif := func(brand string, w, h uint32) []byte {
+		b := append([]byte{0, 0, 0, 16}, "ftyp"+brand+"\x00\x00\x00\x00"...)
+		b = append(b, 0, 0, 0, 20)
+		b = append(b, "ispe\x00\x00\x00\x00"...)
+		b = binary.BigEndian.AppendUint32(b, w)
+		return binary.BigEndian.AppendUint32(b, h)
+	}
+
+	tests := []struct {
+		name    string
+		b       []byte
+		want    Metadata
+		wantErr bool
+	}{
+		{
+			name: "png",
+			b:    encodePNG(32, 16),
+			want: Metadata{Format: structures.EmoteFormatNamePNG, Width: 32, Height: 16, FrameCount: 1},
+		},
+		{
+			name: "apng",
+			b:    apng(32, 32, 24),
+			want: Metadata{Format: structures.EmoteFormatNamePNG, Width: 32, Height: 32, FrameCount: 24},
+		},
+		{
+			name:    "apng with too many frames",
+			b:       apng(32, 32, MAX_FRAME_COUNT+1),
+			wantErr: true,
+		},
+		{
+			name:    "apng without frames",
+			b:       apng(32, 32, 0),
+			wantErr: true,
+		},
+		{
+			name: "gif",
+			b:    encodeGIF(28, 14, 1),
+			want: Metadata{Format: structures.EmoteFormatNameGIF, Width: 28, Height: 14, FrameCount: 1},
+		},
+		{
+			name: "animated gif",
+			b:    encodeGIF(28, 28, 5),
+			want: Metadata{Format: structures.EmoteFormatNameGIF, Width: 28, Height: 28, FrameCount: 5},
+		},
+		{
+			name:    "truncated gif",
+			b:       encodeGIF(28, 28, 5)[:60],
+			wantErr: true,
+		},
+		{
+			name: "lossless webp",
+			b:    riff(vp8l(100, 50)),
+			want: Metadata{Format: structures.EmoteFormatNameWEBP, Width: 100, Height: 50, FrameCount: 1},
+		},
+		{
+			name: "animated webp",
+			b:    riff(vp8x(300, 200), chunk("ANIM", make([]byte, 6)), chunk("ANMF", make([]byte, 17)), chunk("ANMF", make([]byte, 17))),
+			want: Metadata{Format: structures.EmoteFormatNameWEBP, Width: 300, Height: 200, FrameCount: 2},
+		},
+		{
+			name:    "webp without image",
+			b:       riff(chunk("EXIF", make([]byte, 4))),
+			wantErr: true,
+		},
+		{
+			name:    "truncated webp",
+			b:       riff(vp8l(100, 50))[:24],
+			wantErr: true,
+		},
+		{
+			name: "avif",
+			b:    avif("avif", 64, 48),
+			want: Metadata{Format: structures.EmoteFormatNameAVIF, Width: 64, Height: 48, FrameCount: 1},
+		},
+		{
+			name: "avif sequence",
+			b:    avif("avis", 64, 64),
+			want: Metadata{Format: structures.EmoteFormatNameAVIF, Width: 64, Height: 64, FrameCount: 2},
+		},
+		{
+			name:    "avif without dimensions",
+			b:       avif("avif", 64, 64)[:20],
+			wantErr: true,
+		},
+		{
+			name:    "avif with zero dimensions",
+			b:       avif("avif", 0, 64),
+			wantErr: true,
+		},
+		{
+			name:    "other isobmff brand",
+			b:       avif("mp42", 64, 64),
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			b:       []byte("BM\x3a\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00"),
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			b:       nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeMetadata(tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeMetadata() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("DecodeMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
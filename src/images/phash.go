@@ -0,0 +1,121 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/bits"
+	"os"
+	"os/exec"
+)
+
+const (
+	// How many frames of an animated image are hashed
+	PHASH_SAMPLE_FRAMES = 8
+	// The number of differing bits under which images are considered alike
+	PHASH_SIMILARITY_THRESHOLD = 6
+)
+
+// Hashes are split into 8 bands of 8 bits to look up candidates.
+// Two hashes within 7 bits of each other always share a band, beyond that matches may be missed
+const phashBandCount = 8
+
+// PHASH_MAX_THRESHOLD is the largest threshold at which every similar image is found by its bands
+const PHASH_MAX_THRESHOLD = phashBandCount - 1
+
+// PerceptualHashMinSharedBands returns how many bands two hashes within threshold bits of each other share at least,
+// as the bits which differ can only be in as many bands
+func PerceptualHashMinSharedBands(threshold int) int {
+	if threshold >= phashBandCount {
+		return 0
+	}
+	return phashBandCount - threshold
+}
+
+// PerceptualHashes computes a difference hash for the first frame of an image and, if animated, a sample of its other frames.
+//
+// Frames are scaled down to 9x8 in grayscale and each bit of the hash is set when a pixel is brighter than its right neighbour,
+// so that the hashes of resized, recompressed or recoloured copies of an image are within a few bits of each other
+func PerceptualHashes(ctx context.Context, ffmpeg string, b []byte, frameCount int) ([]int64, error) {
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+
+	f, err := os.CreateTemp("", "phash-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return nil, err
+	}
+	f.Close()
+
+	step := frameCount / PHASH_SAMPLE_FRAMES
+	if step < 1 {
+		step = 1
+	}
+	out := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, ffmpeg,
+		"-hide_banner", "-loglevel", "error", "-i", f.Name(),
+		"-vf", fmt.Sprintf("select=not(mod(n\\,%d)),scale=9:8:flags=area,format=gray", step),
+		"-vsync", "vfr", "-frames:v", fmt.Sprint(PHASH_SAMPLE_FRAMES),
+		"-f", "rawvideo", "pipe:1",
+	)
+	cmd.Stdout = &out
+	if err = cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	hashes := []int64{}
+	for frame := out.Bytes(); len(frame) >= 72; frame = frame[72:] {
+		hashes = append(hashes, int64(differenceHash(frame[:72])))
+	}
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("no frames")
+	}
+	return hashes, nil
+}
+
+func differenceHash(gray []byte) uint64 {
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if gray[y*9+x] > gray[y*9+x+1] {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+// PerceptualHashBands returns the bands of a set of hashes, which are stored and indexed alongside them
+func PerceptualHashBands(hashes []int64) []int32 {
+	seen := map[int32]bool{}
+	result := []int32{}
+	for _, h := range hashes {
+		for i := 0; i < phashBandCount; i++ {
+			band := int32(i<<8) | int32(uint64(h)>>(i*8)&0xff)
+			if !seen[band] {
+				seen[band] = true
+				result = append(result, band)
+			}
+		}
+	}
+	return result
+}
+
+// PerceptualHashDistance returns the smallest number of differing bits between any two hashes of the sets
+func PerceptualHashDistance(a []int64, b []int64) int {
+	min := 64
+	for _, x := range a {
+		for _, y := range b {
+			if d := bits.OnesCount64(uint64(x ^ y)); d < min {
+				min = d
+			}
+		}
+	}
+	return min
+}
@@ -0,0 +1,177 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDifferenceHash(t *testing.T) {
+	// gray returns a 9x8 frame with each pixel set by f
+	gray := func(f func(x, y int) byte) []byte {
+		b := make([]byte, 72)
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 9; x++ {
+				b[y*9+x] = f(x, y)
+			}
+		}
+		return b
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  uint64
+	}{
+		{
+			name:  "flat",
+			frame: gray(func(x, y int) byte { return 128 }),
+			want:  0,
+		},
+		{
+			name:  "brightening to the right",
+			frame: gray(func(x, y int) byte { return byte(x * 20) }),
+			want:  0,
+		},
+		{
+			name:  "darkening to the right",
+			frame: gray(func(x, y int) byte { return byte(255 - x*20) }),
+			want:  0xffffffffffffffff,
+		},
+		{
+			name: "first row darkening",
+			frame: gray(func(x, y int) byte {
+				if y == 0 {
+					return byte(255 - x*20)
+				}
+				return 0
+			}),
+			want: 0xff00000000000000,
+		},
+		{
+			name: "bright last column",
+			frame: gray(func(x, y int) byte {
+				if x == 8 {
+					return 255
+				}
+				return 0
+			}),
+			want: 0,
+		},
+		{
+			name: "bright first column",
+			frame: gray(func(x, y int) byte {
+				if x == 0 {
+					return 255
+				}
+				return 0
+			}),
+			want: 0x8080808080808080,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := differenceHash(tt.frame); got != tt.want {
+				t.Errorf("differenceHash() = %#016x, want %#016x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerceptualHashBands(t *testing.T) {
+	tests := []struct {
+		name   string
+		hashes []int64
+		want   []int32
+	}{
+		{
+			name:   "none",
+			hashes: nil,
+			want:   []int32{},
+		},
+		{
+			name:   "zero",
+			hashes: []int64{0},
+			want:   []int32{0x000, 0x100, 0x200, 0x300, 0x400, 0x500, 0x600, 0x700},
+		},
+		{
+			name:   "one byte per band",
+			hashes: []int64{0x0102030405060708},
+			want:   []int32{0x008, 0x107, 0x206, 0x305, 0x404, 0x503, 0x602, 0x701},
+		},
+		{
+			name:   "negative",
+			hashes: []int64{-1},
+			want:   []int32{0x0ff, 0x1ff, 0x2ff, 0x3ff, 0x4ff, 0x5ff, 0x6ff, 0x7ff},
+		},
+		{
+			name:   "shared bands once",
+			hashes: []int64{0, 0x00ff},
+			want:   []int32{0x000, 0x100, 0x200, 0x300, 0x400, 0x500, 0x600, 0x700, 0x0ff},
+		},
+		{
+			name:   "duplicate hashes",
+			hashes: []int64{0x0102030405060708, 0x0102030405060708},
+			want:   []int32{0x008, 0x107, 0x206, 0x305, 0x404, 0x503, 0x602, 0x701},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PerceptualHashBands(tt.hashes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PerceptualHashBands() = %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerceptualHashBandsShared(t *testing.T) {
+	// Hashes within PHASH_MAX_THRESHOLD bits of each other must share a band to be found as candidates
+	base := int64(0x5a5a5a5a5a5a5a5a)
+	for bits := 0; bits <= PHASH_MAX_THRESHOLD; bits++ {
+		h := base
+		for i := 0; i < bits; i++ {
+			h ^= 1 << (i * 8)
+		}
+
+		shared := 0
+		a := map[int32]bool{}
+		for _, band := range PerceptualHashBands([]int64{base}) {
+			a[band] = true
+		}
+		for _, band := range PerceptualHashBands([]int64{h}) {
+			if a[band] {
+				shared++
+			}
+		}
+		if min := PerceptualHashMinSharedBands(bits); shared < min || min < 1 {
+			t.Errorf("hashes %d bits apart share %d bands, want at least %d", bits, shared, min)
+		}
+	}
+}
+
+func TestPerceptualHashDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []int64
+		b    []int64
+		want int
+	}{
+		{name: "equal", a: []int64{0x1234}, b: []int64{0x1234}, want: 0},
+		{name: "one bit", a: []int64{0}, b: []int64{1 << 40}, want: 1},
+		{name: "opposite", a: []int64{0}, b: []int64{-1}, want: 64},
+		{name: "closest frames", a: []int64{0, -1}, b: []int64{0x0f, 0x7fffffffffffffff}, want: 1},
+		{name: "empty", a: nil, b: []int64{0}, want: 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PerceptualHashDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("PerceptualHashDistance() = %d, want %d", got, tt.want)
+			}
+			if got := PerceptualHashDistance(tt.b, tt.a); got != tt.want {
+				t.Errorf("PerceptualHashDistance() is not symmetric: %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
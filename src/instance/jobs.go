@@ -0,0 +1,18 @@
+package instance
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmoteJob is a request for an uploaded emote version to be processed
+type EmoteJob struct {
+	EmoteID   primitive.ObjectID `json:"emote_id"`
+	VersionID primitive.ObjectID `json:"version_id"`
+}
+
+// Jobs hands off work which should be processed outside of a request
+type Jobs interface {
+	EnqueueEmote(ctx context.Context, job EmoteJob) error
+}
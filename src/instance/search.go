@@ -0,0 +1,42 @@
+package instance
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmoteSearchOptions describes a search of live emotes by name and tags
+type EmoteSearchOptions struct {
+	Query         string
+	CaseSensitive bool
+	IgnoreTags    bool
+
+	Animated      *bool
+	ZeroWidth     *bool
+	OwnerID       *primitive.ObjectID
+	MinChannels   int
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// When set, only emotes with one of these versions are matched
+	VersionIDs []primitive.ObjectID
+	// Whether private and unlisted emotes are matched
+	IncludeHidden bool
+
+	// One of "name", "channel_count" or "created_at"
+	Sort       string
+	Descending bool
+	Offset     int
+	Limit      int
+}
+
+// SearchIndex answers emote searches without going to the database
+type SearchIndex interface {
+	// Ready returns whether or not the index has been built and can be queried
+	Ready() bool
+	// SearchEmotes returns the IDs of the default versions of matching emotes, along with the total number of matches
+	SearchEmotes(ctx context.Context, opt EmoteSearchOptions) ([]primitive.ObjectID, int, error)
+	// Refresh reads emotes again, for changes which do not publish an emote event such as their channel count
+	Refresh(ctx context.Context, ids ...primitive.ObjectID)
+}
@@ -0,0 +1,13 @@
+package instance
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Stats keeps a daily history of the usage of emotes
+type Stats interface {
+	// RecordEmote counts an emote being added to (positive delta) or removed from (negative delta) an emote set
+	RecordEmote(ctx context.Context, emoteID primitive.ObjectID, delta int) error
+}
@@ -0,0 +1,10 @@
+package instance
+
+import "context"
+
+// Storage is a blob store for uploaded files
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,15 @@
+package instance
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Trending ranks emotes by how quickly they are being added to emote sets
+type Trending interface {
+	// Record notes an emote being added to (positive delta) or removed from (negative delta) an emote set
+	Record(ctx context.Context, emoteID primitive.ObjectID, delta int) error
+	// Ranking returns the IDs of the highest scoring emotes, best first
+	Ranking(ctx context.Context, limit int) ([]primitive.ObjectID, error)
+}
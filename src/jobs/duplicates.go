@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/src/emotes"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/images"
+	"github.com/SevenTV/GQL/src/instance"
+	"github.com/SevenTV/GQL/src/storage"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// hashEmote computes the perceptual hashes of a processed emote version from its stored files, allowing it to be found by similarity,
+// and compares them with the emotes which were deleted or rejected. It returns the removed emote the version resembles, if any
+func hashEmote(ctx context.Context, gCtx global.Context, job instance.EmoteJob, formats []structures.EmoteFormat, frameCount int) (*emotes.SimilarVersion, error) {
+	cfg := gCtx.Config().Emotes
+
+	// Hash the largest lossless file, which every upload is encoded to
+	name := ""
+	for _, f := range formats {
+		if len(f.Files) > 0 && (name == "" || f.Name == structures.EmoteFormatNamePNG) {
+			name = f.Files[len(f.Files)-1].Name
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	b, err := gCtx.Inst().Storage.Get(ctx, storage.EmoteFileKey(job.VersionID, name))
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := images.PerceptualHashes(ctx, gCtx.Config().Jobs.FFmpegPath, b, frameCount)
+	if err != nil {
+		return nil, err
+	}
+	if err = updateEmoteVersion(ctx, gCtx, job, bson.M{
+		"versions.$.phash":       hashes,
+		"versions.$.phash_bands": images.PerceptualHashBands(hashes),
+	}); err != nil {
+		return nil, err
+	}
+	if cfg.DuplicateAction == "off" {
+		return nil, nil
+	}
+
+	threshold := cfg.DuplicateThreshold
+	if threshold <= 0 {
+		threshold = images.PHASH_SIMILARITY_THRESHOLD
+	}
+	similar, err := emotes.FindSimilarVersions(ctx, gCtx, hashes, job.EmoteID, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ver := range similar {
+		if ver.State.Lifecycle != structures.EmoteLifecycleDeleted && ver.Review != emotes.ReviewStateRejected {
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"emote_id":         job.EmoteID.Hex(),
+			"version_id":       job.VersionID.Hex(),
+			"removed_emote_id": ver.EmoteID.Hex(),
+			"distance":         ver.Distance,
+		}).Info("upload resembles a removed emote")
+		return &ver, nil
+	}
+	return nil, nil
+}
+
+// duplicateChanges returns the changes to make to a version resembling a removed emote, according to the configured action.
+// Moderators can tell what it resembles from its duplicate_of field.
+//
+// With "warn" the version awaits review as usual, with "block" it is rejected as a moderator would
+func duplicateChanges(gCtx global.Context, dup *emotes.SimilarVersion) bson.M {
+	update := bson.M{"versions.$.duplicate_of": dup.EmoteID}
+	if gCtx.Config().Emotes.DuplicateAction == "block" {
+		update["versions.$.state.lifecycle"] = structures.EmoteLifecycleDisabled
+		update["versions.$.state.listed"] = false
+		update["versions.$.review"] = emotes.ReviewStateRejected
+	}
+	return update
+}
+
+// notifyDuplicate lets the owner of a version resembling a removed emote know, once its changes are written.
+// Blocked versions have their rejection recorded as if decided by a moderator
+func notifyDuplicate(ctx context.Context, gCtx global.Context, job instance.EmoteJob, dup *emotes.SimilarVersion) {
+	emote := &structures.Emote{}
+	if err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{"_id": job.EmoteID}).Decode(emote); err != nil {
+		logrus.WithError(err).WithField("emote_id", job.EmoteID.Hex()).Error("mongo, failed to fetch emote")
+		return
+	}
+	for _, ver := range emote.Versions {
+		if ver.ID != job.VersionID {
+			continue
+		}
+
+		if gCtx.Config().Emotes.DuplicateAction == "block" {
+			emotes.Decide(ctx, gCtx, nil, emote, ver, false, "This emote resembles one which was removed")
+		} else {
+			emotes.NotifyOwner(ctx, gCtx, nil, emote, ver, "inbox.generic.emote_duplicate", false, map[string]string{
+				"DUPLICATE_OF": dup.EmoteID.Hex(),
+			})
+		}
+		return
+	}
+}
@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"fmt"
+
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/instance"
+)
+
+// New creates the job queue specified in config
+func New(gCtx global.Context) (instance.Jobs, error) {
+	cfg := gCtx.Config().Jobs
+
+	switch cfg.Type {
+	case "", "redis":
+		return NewRedis(gCtx), nil
+	case "local":
+		return NewLocal(gCtx, cfg.Workers), nil
+	}
+
+	return nil, fmt.Errorf("unknown jobs type: %s", cfg.Type)
+}
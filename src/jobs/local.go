@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/instance"
+	"github.com/sirupsen/logrus"
+)
+
+type localJobs struct {
+	gCtx global.Context
+	ch   chan instance.EmoteJob
+}
+
+// NewLocal creates a job queue which processes jobs in-process
+//
+// This is a stand-in for the processing service, intended for development
+func NewLocal(gCtx global.Context, workers int) instance.Jobs {
+	if workers < 1 {
+		workers = 1
+	}
+
+	q := &localJobs{
+		gCtx: gCtx,
+		ch:   make(chan instance.EmoteJob, 64),
+	}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+
+	return q
+}
+
+func (q *localJobs) EnqueueEmote(ctx context.Context, job instance.EmoteJob) error {
+	select {
+	case q.ch <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *localJobs) work() {
+	for {
+		select {
+		case <-q.gCtx.Done():
+			return
+		case job := <-q.ch:
+			ctx, cancel := context.WithTimeout(q.gCtx, time.Minute*5)
+			if err := processEmote(ctx, q.gCtx, job); err != nil {
+				logrus.WithError(err).WithField("emote_id", job.EmoteID.Hex()).Error("jobs, failed to process emote")
+			}
+			cancel()
+		}
+	}
+}
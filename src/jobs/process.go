@@ -0,0 +1,221 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/src/api/events"
+	"github.com/SevenTV/GQL/src/emotes"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/images"
+	"github.com/SevenTV/GQL/src/instance"
+	"github.com/SevenTV/GQL/src/storage"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// emoteSizes are the heights of the 1x, 2x, 3x and 4x files of an emote
+var emoteSizes = []int{32, 64, 96, 128}
+
+type emoteEncoder struct {
+	format structures.EmoteFormatName
+	ext    string
+	args   func(height int, animated bool) []string
+}
+
+var emoteEncoders = []emoteEncoder{
+	{
+		format: structures.EmoteFormatNameAVIF,
+		ext:    "avif",
+		args: func(height int, animated bool) []string {
+			// av1 requires even dimensions
+			args := []string{"-vf", fmt.Sprintf("scale=-2:%d:flags=lanczos", height), "-c:v", "libaom-av1", "-crf", "30", "-b:v", "0", "-cpu-used", "6"}
+			if !animated {
+				args = append(args, "-still-picture", "1", "-frames:v", "1")
+			}
+			return append(args, "-f", "avif")
+		},
+	},
+	{
+		format: structures.EmoteFormatNameWEBP,
+		ext:    "webp",
+		args: func(height int, animated bool) []string {
+			args := []string{"-vf", fmt.Sprintf("scale=-1:%d:flags=lanczos", height), "-quality", "80"}
+			if animated {
+				args = append(args, "-c:v", "libwebp_anim", "-loop", "0")
+			} else {
+				args = append(args, "-c:v", "libwebp", "-frames:v", "1")
+			}
+			return append(args, "-f", "webp")
+		},
+	},
+	{
+		format: structures.EmoteFormatNameGIF,
+		ext:    "gif",
+		args: func(height int, animated bool) []string {
+			args := []string{
+				"-filter_complex", fmt.Sprintf("scale=-1:%d:flags=lanczos,split[a][b];[a]palettegen=reserve_transparent=1[p];[b][p]paletteuse", height),
+				"-loop", "0",
+			}
+			if !animated {
+				args = append(args, "-frames:v", "1")
+			}
+			return append(args, "-f", "gif")
+		},
+	},
+	{
+		format: structures.EmoteFormatNamePNG,
+		ext:    "png",
+		args: func(height int, animated bool) []string {
+			args := []string{"-vf", fmt.Sprintf("scale=-1:%d:flags=lanczos", height)}
+			if animated {
+				return append(args, "-plays", "0", "-f", "apng")
+			}
+			return append(args, "-frames:v", "1", "-c:v", "png", "-f", "image2")
+		},
+	},
+}
+
+// processEmote transcodes the original upload of an emote version into each format and size,
+// publishing an event as the version progresses through its lifecycle
+func processEmote(ctx context.Context, gCtx global.Context, job instance.EmoteJob) error {
+	if err := updateEmoteVersion(ctx, gCtx, job, bson.M{
+		"versions.$.state.lifecycle": structures.EmoteLifecycleProcessing,
+	}); err != nil {
+		return err
+	}
+
+	formats, frameCount, err := transcodeEmote(ctx, gCtx, job)
+	lifecycle := structures.EmoteLifecycleLive
+	if err != nil || len(formats) == 0 {
+		lifecycle = structures.EmoteLifecycleFailed
+	}
+
+	update := bson.M{"versions.$.state.lifecycle": lifecycle}
+	var dup *emotes.SimilarVersion
+	if lifecycle == structures.EmoteLifecycleLive {
+		// Versions resembling a removed emote are flagged, and may be rejected as a moderator would
+		var herr error
+		if dup, herr = hashEmote(ctx, gCtx, job, formats, frameCount); herr != nil {
+			// Not being able to hash an image shouldn't prevent its processing
+			logrus.WithError(herr).WithField("version_id", job.VersionID.Hex()).Warn("jobs, failed to hash emote")
+		}
+		if dup != nil {
+			for k, v := range duplicateChanges(gCtx, dup) {
+				update[k] = v
+			}
+		}
+	}
+	if frameCount > 0 {
+		update["versions.$.frame_count"] = frameCount
+	}
+	if uerr := updateEmoteVersion(ctx, gCtx, job, update); uerr != nil {
+		return uerr
+	}
+	if dup != nil {
+		notifyDuplicate(ctx, gCtx, job, dup)
+	}
+
+	return err
+}
+
+func transcodeEmote(ctx context.Context, gCtx global.Context, job instance.EmoteJob) ([]structures.EmoteFormat, int, error) {
+	b, err := gCtx.Inst().Storage.Get(ctx, storage.EmoteOriginalKey(job.VersionID))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	meta, err := images.DecodeMetadata(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	if meta.Width <= 0 || meta.Height <= 0 {
+		return nil, 0, fmt.Errorf("invalid image dimensions %dx%d", meta.Width, meta.Height)
+	}
+
+	// ffmpeg reads from and writes to a scratch directory
+	dir, err := os.MkdirTemp("", "emote-"+job.VersionID.Hex())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "original")
+	if err = os.WriteFile(in, b, 0o600); err != nil {
+		return nil, 0, err
+	}
+
+	ffmpeg := gCtx.Config().Jobs.FFmpegPath
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+
+	formats := []structures.EmoteFormat{}
+	for _, enc := range emoteEncoders {
+		format := structures.EmoteFormat{Name: enc.format}
+
+		for i, height := range emoteSizes {
+			name := fmt.Sprintf("%dx.%s", i+1, enc.ext)
+			out := filepath.Join(dir, name)
+
+			start := time.Now()
+			args := append([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", in}, enc.args(height, meta.Animated())...)
+			if output, err := exec.CommandContext(ctx, ffmpeg, append(args, out)...).CombinedOutput(); err != nil {
+				logrus.WithError(err).WithField("output", string(output)).Warnf("jobs, failed to encode %s", name)
+				break
+			}
+			elapsed := time.Since(start)
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				return formats, meta.FrameCount, err
+			}
+			if err = gCtx.Inst().Storage.Put(ctx, storage.EmoteFileKey(job.VersionID, name), data, string(enc.format)); err != nil {
+				return formats, meta.FrameCount, err
+			}
+
+			file := structures.EmoteFile{
+				Name:           name,
+				Height:         int32(height),
+				Width:          int32(meta.Width * height / meta.Height),
+				Animated:       meta.Animated(),
+				ProcessingTime: elapsed.Milliseconds(),
+				Length:         int64(len(data)),
+			}
+			if m, err := images.DecodeMetadata(data); err == nil {
+				file.Width, file.Height = int32(m.Width), int32(m.Height)
+			}
+			format.Files = append(format.Files, file)
+		}
+		if len(format.Files) == 0 {
+			continue
+		}
+
+		// Make the format available as soon as it's done
+		formats = append(formats, format)
+		if err = updateEmoteVersion(ctx, gCtx, job, bson.M{"versions.$.formats": formats}); err != nil {
+			return formats, meta.FrameCount, err
+		}
+	}
+
+	return formats, meta.FrameCount, nil
+}
+
+// updateEmoteVersion sets fields of the job's emote version and notifies subscribers of the emote
+func updateEmoteVersion(ctx context.Context, gCtx global.Context, job instance.EmoteJob, set bson.M) error {
+	if _, err := gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).UpdateOne(ctx, bson.M{
+		"_id":         job.EmoteID,
+		"versions.id": job.VersionID,
+	}, bson.M{"$set": set}); err != nil {
+		return err
+	}
+
+	events.Publish(gCtx, "emotes", job.EmoteID)
+	return nil
+}
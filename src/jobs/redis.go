@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/instance"
+)
+
+type redisJobs struct {
+	gCtx global.Context
+}
+
+// NewRedis creates a job queue which pushes jobs onto a redis list, to be consumed by the processing service
+func NewRedis(gCtx global.Context) instance.Jobs {
+	return &redisJobs{gCtx: gCtx}
+}
+
+func (q *redisJobs) EnqueueEmote(ctx context.Context, job instance.EmoteJob) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	k := q.gCtx.Inst().Redis.ComposeKey("jobs", "emotes")
+	return q.gCtx.Inst().Redis.RawClient().LPush(ctx, k.String(), b).Err()
+}
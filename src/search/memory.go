@@ -0,0 +1,321 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/instance"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const SEARCH_REBUILD_INTERVAL = time.Minute * 30
+
+// emoteDocument is what the index knows of an emote
+type emoteDocument struct {
+	ID             primitive.ObjectID
+	DefaultVersion primitive.ObjectID
+	Versions       []primitive.ObjectID
+	Name           string
+	NameLower      string
+	Tags           []string
+	Flags          structures.EmoteFlag
+	Listed         bool
+	Animated       bool
+	OwnerID        primitive.ObjectID
+	ChannelCount   int
+}
+
+func newEmoteDocument(e *structures.Emote) *emoteDocument {
+	if len(e.Versions) == 0 || e.Versions[0].State.Lifecycle != structures.EmoteLifecycleLive {
+		return nil // only live emotes are searchable
+	}
+
+	doc := &emoteDocument{
+		ID:             e.ID,
+		DefaultVersion: e.Versions[0].ID,
+		Versions:       make([]primitive.ObjectID, len(e.Versions)),
+		Name:           e.Name,
+		NameLower:      strings.ToLower(e.Name),
+		Tags:           e.Tags,
+		Flags:          e.Flags,
+		Listed:         e.Versions[0].State.Listed,
+		Animated:       e.Versions[0].FrameCount > 1,
+		OwnerID:        e.OwnerID,
+	}
+	for i, ver := range e.Versions {
+		doc.Versions[i] = ver.ID
+		doc.ChannelCount += int(ver.State.ChannelCount)
+	}
+
+	return doc
+}
+
+// memoryIndex is an in-process trigram index of emote names and tags
+type memoryIndex struct {
+	gCtx  global.Context
+	mx    sync.RWMutex
+	ready int32
+
+	docs     map[primitive.ObjectID]*emoteDocument
+	trigrams map[string]map[primitive.ObjectID]struct{}
+
+	// Emotes changed while the index was being built, which must be refreshed afterwards
+	pendingMx sync.Mutex
+	pending   map[primitive.ObjectID]struct{}
+}
+
+// NewMemory creates an in-process search index, which is built in the background
+// and kept up to date by emote events
+func NewMemory(gCtx global.Context) instance.SearchIndex {
+	idx := &memoryIndex{
+		gCtx:     gCtx,
+		docs:     map[primitive.ObjectID]*emoteDocument{},
+		trigrams: map[string]map[primitive.ObjectID]struct{}{},
+		pending:  map[primitive.ObjectID]struct{}{},
+	}
+
+	interval := SEARCH_REBUILD_INTERVAL
+	if m := gCtx.Config().Search.RebuildMinutes; m > 0 {
+		interval = time.Duration(m) * time.Minute
+	}
+
+	go idx.listen()
+	go idx.run(interval)
+
+	return idx
+}
+
+func (idx *memoryIndex) Ready() bool {
+	return atomic.LoadInt32(&idx.ready) == 1
+}
+
+func (idx *memoryIndex) SearchEmotes(ctx context.Context, opt instance.EmoteSearchOptions) ([]primitive.ObjectID, int, error) {
+	query := opt.Query
+	queryLower := strings.ToLower(query)
+
+	versions := map[primitive.ObjectID]struct{}{}
+	for _, id := range opt.VersionIDs {
+		versions[id] = struct{}{}
+	}
+
+	idx.mx.RLock()
+	defer idx.mx.RUnlock()
+
+	// Narrow down candidates using the trigrams of the query
+	candidates := idx.docs
+	if grams := trigrams(queryLower); len(grams) > 0 {
+		candidates = map[primitive.ObjectID]*emoteDocument{}
+		for id := range idx.intersect(grams) {
+			candidates[id] = idx.docs[id]
+		}
+	}
+
+	type match struct {
+		doc       *emoteDocument
+		relevance int
+	}
+	matches := []match{}
+	for _, doc := range candidates {
+		if !doc.matches(opt, versions) {
+			continue
+		}
+
+		// Verify the match, as trigrams may match out of order
+		name, q := doc.NameLower, queryLower
+		if opt.CaseSensitive {
+			name, q = doc.Name, query
+		}
+
+		pos := strings.Index(name, q)
+		if pos < 0 && !(!opt.IgnoreTags && doc.hasTag(queryLower)) {
+			continue
+		}
+
+		// Rank exact name matches first, then those starting with the query, then the rest
+		relevance := 0
+		if query != "" {
+			switch {
+			case name == q:
+				relevance = 2
+			case pos == 0:
+				relevance = 1
+			}
+		}
+		matches = append(matches, match{doc, relevance})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.relevance != b.relevance {
+			return a.relevance > b.relevance
+		}
+
+		less, equal := a.doc.compare(b.doc, opt.Sort)
+		if equal {
+			less = bytes.Compare(a.doc.ID[:], b.doc.ID[:]) < 0
+		}
+		if opt.Descending {
+			return !less
+		}
+		return less
+	})
+
+	total := len(matches)
+	if opt.Offset >= total {
+		return []primitive.ObjectID{}, total, nil
+	}
+	matches = matches[opt.Offset:]
+	if opt.Limit > 0 && len(matches) > opt.Limit {
+		matches = matches[:opt.Limit]
+	}
+
+	ids := make([]primitive.ObjectID, len(matches))
+	for i, m := range matches {
+		ids[i] = m.doc.DefaultVersion
+	}
+	return ids, total, nil
+}
+
+// intersect returns the IDs of emotes having all of the given trigrams
+func (idx *memoryIndex) intersect(grams []string) map[primitive.ObjectID]struct{} {
+	// Start from the rarest trigram
+	sort.Slice(grams, func(i, j int) bool {
+		return len(idx.trigrams[grams[i]]) < len(idx.trigrams[grams[j]])
+	})
+
+	result := map[primitive.ObjectID]struct{}{}
+	for id := range idx.trigrams[grams[0]] {
+		result[id] = struct{}{}
+	}
+	for _, g := range grams[1:] {
+		set := idx.trigrams[g]
+		for id := range result {
+			if _, ok := set[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+
+	return result
+}
+
+// put adds, replaces or removes (if doc is nil) an emote in the index. The index must be locked for writing
+func (idx *memoryIndex) put(id primitive.ObjectID, doc *emoteDocument) {
+	if old, ok := idx.docs[id]; ok {
+		for _, g := range old.trigrams() {
+			delete(idx.trigrams[g], id)
+			if len(idx.trigrams[g]) == 0 {
+				delete(idx.trigrams, g)
+			}
+		}
+		delete(idx.docs, id)
+	}
+	if doc == nil {
+		return
+	}
+
+	idx.docs[id] = doc
+	for _, g := range doc.trigrams() {
+		set, ok := idx.trigrams[g]
+		if !ok {
+			set = map[primitive.ObjectID]struct{}{}
+			idx.trigrams[g] = set
+		}
+		set[id] = struct{}{}
+	}
+}
+
+func (doc *emoteDocument) matches(opt instance.EmoteSearchOptions, versions map[primitive.ObjectID]struct{}) bool {
+	if !opt.IncludeHidden && (doc.Flags&structures.EmoteFlagsPrivate != 0 || !doc.Listed) {
+		return false
+	}
+	if opt.Animated != nil && doc.Animated != *opt.Animated {
+		return false
+	}
+	if opt.ZeroWidth != nil && (doc.Flags&structures.EmoteFlagsZeroWidth != 0) != *opt.ZeroWidth {
+		return false
+	}
+	if opt.OwnerID != nil && doc.OwnerID != *opt.OwnerID {
+		return false
+	}
+	if doc.ChannelCount < opt.MinChannels {
+		return false
+	}
+	if opt.CreatedAfter != nil && doc.ID.Timestamp().Before(*opt.CreatedAfter) {
+		return false
+	}
+	if opt.CreatedBefore != nil && !doc.ID.Timestamp().Before(*opt.CreatedBefore) {
+		return false
+	}
+	if opt.VersionIDs != nil {
+		found := false
+		for _, v := range doc.Versions {
+			if _, found = versions[v]; found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (doc *emoteDocument) hasTag(q string) bool {
+	for _, t := range doc.Tags {
+		if strings.Contains(t, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare returns whether doc sorts before other by the given field, and whether they are equal
+func (doc *emoteDocument) compare(other *emoteDocument, field string) (bool, bool) {
+	switch field {
+	case "name":
+		return doc.NameLower < other.NameLower, doc.NameLower == other.NameLower
+	case "created_at", "age":
+		return false, true // ties are broken by ID, which is the creation date
+	default:
+		return doc.ChannelCount < other.ChannelCount, doc.ChannelCount == other.ChannelCount
+	}
+}
+
+// trigrams returns the distinct trigrams of the emote's name and tags
+func (doc *emoteDocument) trigrams() []string {
+	seen := map[string]struct{}{}
+	result := []string{}
+	for _, s := range append([]string{doc.NameLower}, doc.Tags...) {
+		for _, g := range trigrams(s) {
+			if _, ok := seen[g]; !ok {
+				seen[g] = struct{}{}
+				result = append(result, g)
+			}
+		}
+	}
+
+	return result
+}
+
+// trigrams splits a string into each sequence of three characters
+func trigrams(s string) []string {
+	r := []rune(s)
+	if len(r) < 3 {
+		return nil
+	}
+
+	result := make([]string, 0, len(r)-2)
+	for i := 0; i+3 <= len(r); i++ {
+		result = append(result, string(r[i:i+3]))
+	}
+	return result
+}
@@ -0,0 +1,262 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/SevenTV/GQL/src/instance"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryIndexSearchEmotes(t *testing.T) {
+	base := time.Unix(1600000000, 0)
+	at := func(i int) time.Time { return base.Add(time.Duration(i) * time.Hour) }
+	ownerA, ownerB := primitive.NewObjectID(), primitive.NewObjectID()
+
+	idx := &memoryIndex{
+		docs:     map[primitive.ObjectID]*emoteDocument{},
+		trigrams: map[string]map[primitive.ObjectID]struct{}{},
+	}
+	names := map[primitive.ObjectID]string{}
+	versionOf := map[string][]primitive.ObjectID{}
+	add := func(i int, name string, owner primitive.ObjectID, flags structures.EmoteFlag, tags []string, frames int32, listed bool, lifecycle structures.EmoteLifecycle, channels ...int32) {
+		e := &structures.Emote{
+			ID:      primitive.NewObjectIDFromTimestamp(at(i)),
+			OwnerID: owner,
+			Name:    name,
+			Flags:   flags,
+			Tags:    tags,
+		}
+		for _, n := range channels {
+			ver := &structures.EmoteVersion{
+				ID:         primitive.NewObjectID(),
+				FrameCount: frames,
+				State:      structures.EmoteVersionState{Lifecycle: lifecycle, Listed: listed, ChannelCount: n},
+			}
+			e.Versions = append(e.Versions, ver)
+			names[ver.ID] = name
+			versionOf[name] = append(versionOf[name], ver.ID)
+		}
+		if doc := newEmoteDocument(e); doc != nil {
+			idx.put(e.ID, doc)
+		}
+	}
+	add(1, "peepoHappy", ownerA, 0, []string{"frog"}, 1, true, structures.EmoteLifecycleLive, 4, 6)
+	add(2, "peepoSad", ownerB, 0, []string{"frog", "sad"}, 10, true, structures.EmoteLifecycleLive, 50)
+	add(3, "Happy", ownerB, structures.EmoteFlagsZeroWidth, nil, 1, true, structures.EmoteLifecycleLive, 5)
+	add(4, "peepoPrivate", ownerA, structures.EmoteFlagsPrivate, nil, 1, true, structures.EmoteLifecycleLive, 1)
+	add(5, "peepoUnlisted", ownerA, 0, nil, 1, false, structures.EmoteLifecycleLive, 2)
+	add(6, "peepoNew", ownerA, 0, nil, 1, true, structures.EmoteLifecycleProcessing, 0)
+
+	yes := true
+	after := at(2)
+
+	tests := []struct {
+		name      string
+		opt       instance.EmoteSearchOptions
+		want      []string
+		wantTotal int
+	}{
+		{
+			name:      "everything by channel count",
+			opt:       instance.EmoteSearchOptions{Descending: true},
+			want:      []string{"peepoSad", "peepoHappy", "Happy"},
+			wantTotal: 3,
+		},
+		{
+			name:      "name",
+			opt:       instance.EmoteSearchOptions{Query: "peepo", Descending: true},
+			want:      []string{"peepoSad", "peepoHappy"},
+			wantTotal: 2,
+		},
+		{
+			name:      "short query",
+			opt:       instance.EmoteSearchOptions{Query: "pe", Sort: "name"},
+			want:      []string{"peepoHappy", "peepoSad"},
+			wantTotal: 2,
+		},
+		{
+			name:      "hidden emotes, by name",
+			opt:       instance.EmoteSearchOptions{Query: "peepo", IncludeHidden: true, Sort: "name"},
+			want:      []string{"peepoHappy", "peepoPrivate", "peepoSad", "peepoUnlisted"},
+			wantTotal: 4,
+		},
+		{
+			name:      "exact matches first",
+			opt:       instance.EmoteSearchOptions{Query: "happy", Descending: true},
+			want:      []string{"Happy", "peepoHappy"},
+			wantTotal: 2,
+		},
+		{
+			name:      "exact matches first in any order",
+			opt:       instance.EmoteSearchOptions{Query: "happy"},
+			want:      []string{"Happy", "peepoHappy"},
+			wantTotal: 2,
+		},
+		{
+			name:      "case sensitive",
+			opt:       instance.EmoteSearchOptions{Query: "Happy", CaseSensitive: true, Sort: "name"},
+			want:      []string{"Happy", "peepoHappy"},
+			wantTotal: 2,
+		},
+		{
+			name:      "case sensitive without match",
+			opt:       instance.EmoteSearchOptions{Query: "HAPPY", CaseSensitive: true, IgnoreTags: true},
+			want:      []string{},
+			wantTotal: 0,
+		},
+		{
+			name:      "tags",
+			opt:       instance.EmoteSearchOptions{Query: "frog", Sort: "name"},
+			want:      []string{"peepoHappy", "peepoSad"},
+			wantTotal: 2,
+		},
+		{
+			name:      "tags ignored",
+			opt:       instance.EmoteSearchOptions{Query: "frog", IgnoreTags: true},
+			want:      []string{},
+			wantTotal: 0,
+		},
+		{
+			name:      "animated",
+			opt:       instance.EmoteSearchOptions{Animated: &yes},
+			want:      []string{"peepoSad"},
+			wantTotal: 1,
+		},
+		{
+			name:      "zero width",
+			opt:       instance.EmoteSearchOptions{ZeroWidth: &yes},
+			want:      []string{"Happy"},
+			wantTotal: 1,
+		},
+		{
+			name:      "owner",
+			opt:       instance.EmoteSearchOptions{OwnerID: &ownerA, IncludeHidden: true, Sort: "created_at"},
+			want:      []string{"peepoHappy", "peepoPrivate", "peepoUnlisted"},
+			wantTotal: 3,
+		},
+		{
+			name:      "channel count summed over versions",
+			opt:       instance.EmoteSearchOptions{MinChannels: 10, Sort: "name"},
+			want:      []string{"peepoHappy", "peepoSad"},
+			wantTotal: 2,
+		},
+		{
+			name:      "created after",
+			opt:       instance.EmoteSearchOptions{CreatedAfter: &after, Sort: "created_at"},
+			want:      []string{"peepoSad", "Happy"},
+			wantTotal: 2,
+		},
+		{
+			name:      "created before",
+			opt:       instance.EmoteSearchOptions{CreatedBefore: &after, Sort: "created_at"},
+			want:      []string{"peepoHappy"},
+			wantTotal: 1,
+		},
+		{
+			name:      "versions",
+			opt:       instance.EmoteSearchOptions{VersionIDs: []primitive.ObjectID{versionOf["peepoHappy"][1], versionOf["Happy"][0]}, Sort: "name"},
+			want:      []string{"Happy", "peepoHappy"},
+			wantTotal: 2,
+		},
+		{
+			name:      "no versions",
+			opt:       instance.EmoteSearchOptions{VersionIDs: []primitive.ObjectID{}},
+			want:      []string{},
+			wantTotal: 0,
+		},
+		{
+			name:      "page",
+			opt:       instance.EmoteSearchOptions{Descending: true, Offset: 1, Limit: 1},
+			want:      []string{"peepoHappy"},
+			wantTotal: 3,
+		},
+		{
+			name:      "past the last page",
+			opt:       instance.EmoteSearchOptions{Offset: 3, Limit: 1},
+			want:      []string{},
+			wantTotal: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, total, err := idx.SearchEmotes(context.Background(), tt.opt)
+			if err != nil {
+				t.Fatalf("SearchEmotes() error = %v", err)
+			}
+
+			got := make([]string, len(ids))
+			for i, id := range ids {
+				got[i] = names[id]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchEmotes() = %v, want %v", got, tt.want)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexPut(t *testing.T) {
+	id := primitive.NewObjectID()
+	emote := func(name string, tags ...string) *structures.Emote {
+		return &structures.Emote{ID: id, Name: name, Tags: tags, Versions: []*structures.EmoteVersion{{
+			ID:    primitive.NewObjectID(),
+			State: structures.EmoteVersionState{Lifecycle: structures.EmoteLifecycleLive, Listed: true},
+		}}}
+	}
+
+	tests := []struct {
+		name  string
+		emote *structures.Emote
+		query string
+		want  int
+	}{
+		{name: "added", emote: emote("peepoHappy"), query: "happy", want: 1},
+		{name: "renamed", emote: emote("peepoSad"), query: "happy", want: 0},
+		{name: "tagged", emote: emote("peepoSad", "happy"), query: "happy", want: 1},
+		{name: "no longer live", emote: &structures.Emote{ID: id, Name: "peepoHappy"}, query: "happy", want: 0},
+	}
+
+	idx := &memoryIndex{
+		docs:     map[primitive.ObjectID]*emoteDocument{},
+		trigrams: map[string]map[primitive.ObjectID]struct{}{},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx.put(id, newEmoteDocument(tt.emote))
+
+			_, total, err := idx.SearchEmotes(context.Background(), instance.EmoteSearchOptions{Query: tt.query})
+			if err != nil {
+				t.Fatalf("SearchEmotes() error = %v", err)
+			}
+			if total != tt.want {
+				t.Errorf("total = %d, want %d", total, tt.want)
+			}
+
+			// Only the trigrams of the current document may refer to the emote
+			want := map[string]bool{}
+			if doc := idx.docs[id]; doc != nil {
+				for _, g := range doc.trigrams() {
+					want[g] = true
+				}
+			}
+			for g, set := range idx.trigrams {
+				if _, ok := set[id]; ok != want[g] {
+					t.Errorf("trigram %q refers to the emote: %v, want %v", g, ok, want[g])
+				}
+			}
+			for g := range want {
+				if _, ok := idx.trigrams[g][id]; !ok {
+					t.Errorf("trigram %q is missing", g)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/instance"
+)
+
+// New creates the search index specified in config
+//
+// A nil index means that searches are made against the database
+func New(gCtx global.Context) (instance.SearchIndex, error) {
+	cfg := gCtx.Config().Search
+
+	switch cfg.Type {
+	case "", "memory":
+		return NewMemory(gCtx), nil
+	case "mongo":
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unknown search type: %s", cfg.Type)
+}
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/Common/structures/v3"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// emoteProjection is the subset of emote fields needed to index it
+var emoteProjection = bson.M{
+	"name":                         1,
+	"tags":                         1,
+	"flags":                        1,
+	"owner_id":                     1,
+	"versions.id":                  1,
+	"versions.frame_count":         1,
+	"versions.state.lifecycle":     1,
+	"versions.state.channel_count": 1,
+	"versions.state.listed":        1,
+}
+
+func (idx *memoryIndex) run(interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		start := time.Now()
+		if err := idx.build(idx.gCtx); err != nil {
+			logrus.WithError(err).Error("search, failed to build index")
+		} else {
+			logrus.WithField("took", time.Since(start)).Info("search, index built")
+		}
+
+		select {
+		case <-idx.gCtx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// build reads every live emote into a new index, replacing the current one
+func (idx *memoryIndex) build(ctx context.Context) error {
+	idx.pendingMx.Lock()
+	idx.pending = map[primitive.ObjectID]struct{}{}
+	idx.pendingMx.Unlock()
+
+	cur, err := idx.gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Find(ctx, bson.M{
+		"versions.0.state.lifecycle": structures.EmoteLifecycleLive,
+	}, options.Find().SetProjection(emoteProjection).SetBatchSize(1000))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	built := &memoryIndex{
+		docs:     map[primitive.ObjectID]*emoteDocument{},
+		trigrams: map[string]map[primitive.ObjectID]struct{}{},
+	}
+	for cur.Next(ctx) {
+		e := &structures.Emote{}
+		if err = cur.Decode(e); err != nil {
+			logrus.WithError(err).Warn("search, failed to decode emote")
+			continue
+		}
+		built.put(e.ID, newEmoteDocument(e))
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+
+	idx.mx.Lock()
+	idx.docs, idx.trigrams = built.docs, built.trigrams
+	idx.mx.Unlock()
+
+	// Emotes which changed while reading may have been read before the change
+	idx.pendingMx.Lock()
+	pending := idx.pending
+	idx.pending = nil
+	idx.pendingMx.Unlock()
+	for id := range pending {
+		idx.refresh(ctx, id)
+	}
+
+	atomic.StoreInt32(&idx.ready, 1)
+	return nil
+}
+
+// listen refreshes emotes in the index as they change
+func (idx *memoryIndex) listen() {
+	pattern := idx.gCtx.Inst().Redis.ComposeKey("events", "sub:emotes:*")
+	sub := idx.gCtx.Inst().Redis.RawClient().PSubscribe(idx.gCtx, pattern.String())
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-idx.gCtx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			id, err := primitive.ObjectIDFromHex(msg.Channel[strings.LastIndex(msg.Channel, ":")+1:])
+			if err != nil {
+				continue
+			}
+
+			idx.Refresh(idx.gCtx, id)
+		}
+	}
+}
+
+func (idx *memoryIndex) Refresh(ctx context.Context, ids ...primitive.ObjectID) {
+	for _, id := range ids {
+		// Defer to the end of the build if one is running
+		idx.pendingMx.Lock()
+		if idx.pending != nil {
+			idx.pending[id] = struct{}{}
+		}
+		idx.pendingMx.Unlock()
+
+		idx.refresh(ctx, id)
+	}
+}
+
+// refresh reads an emote and updates it in the index
+func (idx *memoryIndex) refresh(ctx context.Context, id primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	var doc *emoteDocument
+	e := &structures.Emote{}
+	err := idx.gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(emoteProjection)).Decode(e)
+	switch err {
+	case nil:
+		doc = newEmoteDocument(e)
+	case mongo.ErrNoDocuments:
+	default:
+		logrus.WithError(err).WithField("emote_id", id.Hex()).Warn("search, failed to refresh emote")
+		return
+	}
+
+	idx.mx.Lock()
+	idx.put(id, doc)
+	idx.mx.Unlock()
+}
@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/Common/mongo"
+	"github.com/SevenTV/GQL/src/configure"
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/instance"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const STATS_SNAPSHOT_INTERVAL = time.Hour
+
+// SNAPSHOT_MARKER_ID is the emote ID of the documents marking the days on which a snapshot was taken
+var SNAPSHOT_MARKER_ID = primitive.NilObjectID
+
+// Each emote version has one document per day, holding its channel count and the number of times it was added and removed.
+// Channel counts are snapshotted periodically, so that a day's document holds the last count taken on that day.
+// Versions without channels aren't written, so each snapshot also writes a document for SNAPSHOT_MARKER_ID,
+// telling the days on which counts were taken apart from those on which they are unknown
+type mongoStats struct {
+	gCtx global.Context
+}
+
+// New creates a stats recorder backed by mongo and starts taking snapshots of channel counts
+func New(gCtx global.Context) instance.Stats {
+	s := &mongoStats{gCtx: gCtx}
+
+	interval := STATS_SNAPSHOT_INTERVAL
+	if m := gCtx.Config().Stats.SnapshotMinutes; m > 0 {
+		interval = time.Duration(m) * time.Minute
+	}
+	go s.run(interval)
+
+	return s
+}
+
+func (s *mongoStats) RecordEmote(ctx context.Context, emoteID primitive.ObjectID, delta int) error {
+	inc := bson.M{"adds": 1}
+	if delta < 0 {
+		inc = bson.M{"removes": 1}
+	}
+
+	_, err := s.gCtx.Inst().Mongo.Collection(configure.CollectionNameEmoteStats).UpdateOne(ctx, bson.M{
+		"emote_id": emoteID,
+		"date":     Day(time.Now()),
+	}, bson.M{"$inc": inc}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (s *mongoStats) run(interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		if err := s.snapshot(s.gCtx); err != nil {
+			logrus.WithError(err).Error("stats, failed to snapshot channel counts")
+		}
+
+		select {
+		case <-s.gCtx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// snapshot writes the current channel count of every version in use to today's documents
+func (s *mongoStats) snapshot(ctx context.Context) error {
+	cur, err := s.gCtx.Inst().Mongo.Collection(mongo.CollectionNameEmotes).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"versions.state.channel_count": bson.M{"$gt": 0}}}},
+		{{Key: "$unwind", Value: "$versions"}},
+		{{Key: "$match", Value: bson.M{"versions.state.channel_count": bson.M{"$gt": 0}}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":           0,
+			"emote_id":      "$versions.id",
+			"date":          Day(time.Now()),
+			"channel_count": "$versions.state.channel_count",
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           configure.CollectionNameEmoteStats,
+			"on":             bson.A{"emote_id", "date"},
+			"whenMatched":    "merge",
+			"whenNotMatched": "insert",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	if err = cur.Close(ctx); err != nil {
+		return err
+	}
+
+	_, err = s.gCtx.Inst().Mongo.Collection(configure.CollectionNameEmoteStats).UpdateOne(ctx, bson.M{
+		"emote_id": SNAPSHOT_MARKER_ID,
+		"date":     Day(time.Now()),
+	}, bson.M{"$set": bson.M{"channel_count": 0}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// Day returns the start of the UTC day of a time, which stats documents are keyed by
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour * 24)
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmoteOriginalKey is the key under which the original upload of an emote version is stored
+func EmoteOriginalKey(versionID primitive.ObjectID) string {
+	return fmt.Sprintf("emote/%s/original", versionID.Hex())
+}
+
+// EmoteFileKey is the key under which a processed file of an emote version is stored
+func EmoteFileKey(versionID primitive.ObjectID, name string) string {
+	return fmt.Sprintf("emote/%s/%s", versionID.Hex(), name)
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/instance"
+)
+
+// New creates the storage instance specified in config
+func New(gCtx global.Context) (instance.Storage, error) {
+	cfg := gCtx.Config().Storage
+
+	switch cfg.Type {
+	case "", "local":
+		return NewLocal(cfg.Path)
+	}
+
+	return nil, fmt.Errorf("unknown storage type: %s", cfg.Type)
+}
@@ -0,0 +1,134 @@
+package trending
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/GQL/src/global"
+	"github.com/SevenTV/GQL/src/instance"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TRENDING_WINDOW_HOURS     = 24
+	TRENDING_REFRESH_INTERVAL = time.Minute * 5
+)
+
+// Set additions are counted in hourly buckets, which are summed over the window into the scores
+const bucketSize = time.Hour
+
+type redisTrending struct {
+	gCtx   global.Context
+	window int
+}
+
+// New creates a trending aggregator backed by redis sorted sets and starts refreshing its scores
+func New(gCtx global.Context) instance.Trending {
+	cfg := gCtx.Config().Trending
+
+	t := &redisTrending{
+		gCtx:   gCtx,
+		window: TRENDING_WINDOW_HOURS,
+	}
+	if cfg.WindowHours > 0 {
+		t.window = cfg.WindowHours
+	}
+
+	interval := TRENDING_REFRESH_INTERVAL
+	if cfg.RefreshSeconds > 0 {
+		interval = time.Duration(cfg.RefreshSeconds) * time.Second
+	}
+	go t.run(interval)
+
+	return t
+}
+
+func (t *redisTrending) Record(ctx context.Context, emoteID primitive.ObjectID, delta int) error {
+	k := t.bucketKey(time.Now().Unix() / int64(bucketSize.Seconds()))
+
+	// Buckets expire once they have left the window
+	pipe := t.gCtx.Inst().Redis.RawClient().TxPipeline()
+	pipe.ZIncrBy(ctx, k, float64(delta), emoteID.Hex())
+	pipe.Expire(ctx, k, bucketSize*time.Duration(t.window+1))
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+func (t *redisTrending) Ranking(ctx context.Context, limit int) ([]primitive.ObjectID, error) {
+	// Emotes which lost more channels than they gained are not trending
+	members, err := t.gCtx.Inst().Redis.RawClient().ZRevRangeByScore(ctx, t.scoresKey(), &redis.ZRangeBy{
+		Min:   "(0",
+		Max:   "+inf",
+		Count: int64(limit),
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(members))
+	for _, m := range members {
+		if id, err := primitive.ObjectIDFromHex(m); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (t *redisTrending) run(interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		if err := t.refresh(t.gCtx); err != nil {
+			logrus.WithError(err).Error("trending, failed to refresh scores")
+		}
+
+		select {
+		case <-t.gCtx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// refresh sums the buckets within the window into the scores,
+// weighing recent buckets more heavily so that the ranking reflects current velocity
+func (t *redisTrending) refresh(ctx context.Context) error {
+	now := time.Now().Unix() / int64(bucketSize.Seconds())
+
+	keys := make([]string, t.window)
+	weights := make([]float64, t.window)
+	for i := 0; i < t.window; i++ {
+		keys[i] = t.bucketKey(now - int64(i))
+		weights[i] = float64(t.window-i) / float64(t.window)
+	}
+
+	// Compute into a temporary key so that readers never see partial scores
+	client := t.gCtx.Inst().Redis.RawClient()
+	tmp := t.scoresKey() + ":tmp"
+	n, err := client.ZUnionStore(ctx, tmp, &redis.ZStore{
+		Keys:      keys,
+		Weights:   weights,
+		Aggregate: "SUM",
+	}).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return client.Del(ctx, t.scoresKey()).Err()
+	}
+
+	return client.Rename(ctx, tmp, t.scoresKey()).Err()
+}
+
+func (t *redisTrending) bucketKey(bucket int64) string {
+	return t.gCtx.Inst().Redis.ComposeKey("trending", fmt.Sprintf("bucket:%d", bucket)).String()
+}
+
+func (t *redisTrending) scoresKey() string {
+	return t.gCtx.Inst().Redis.ComposeKey("trending", "scores").String()
+}